@@ -0,0 +1,99 @@
+package goblog
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PostRef references a neighbouring post.
+type PostRef struct {
+	Slug  string    `json:"slug"`
+	Title string    `json:"title"`
+	Path  string    `json:"path"`
+	Date  time.Time `json:"date"`
+}
+
+func newPostRef(p Post) *PostRef {
+	return &PostRef{
+		Slug:  p.Slug,
+		Title: p.Title,
+		Path:  p.Path,
+		Date:  p.Date,
+	}
+}
+
+// PostResponse is a post with its full contents and
+// references to the posts published around it.
+type PostResponse struct {
+	Post
+	MarkDown string `json:"markdown"`
+	// HTML is the rendered markdown, only present
+	// when requested.
+	HTML string `json:"html,omitempty"`
+	// Prev is the post published before this one.
+	Prev *PostRef `json:"prev,omitempty"`
+	// Next is the post published after this one.
+	Next *PostRef `json:"next,omitempty"`
+}
+
+// PostAPIHandler serves published posts addressed by slug
+// at "/api/posts/{slug}" as a JSON PostResponse.
+//
+// The "format=html" query parameter includes the post's
+// rendered markdown.
+func PostAPIHandler() http.HandlerFunc {
+	const prefix = "/api/posts/"
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		slug := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		if slug == "" {
+			Error(w, r, "no slug provided in path", http.StatusBadRequest)
+			return
+		}
+
+		c := contentOf(r)
+		posts := c.publishedPosts()
+		i, ok := posts.BySlug(slug)
+		if !ok {
+			Error(w, r, "not found", http.StatusNotFound)
+			return
+		}
+
+		post, err := readPost(c.postsFS, posts[i].Path)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp := PostResponse{
+			Post:     posts[i],
+			MarkDown: post.MarkDown.Value,
+		}
+		if r.URL.Query().Get("format") == "html" {
+			resp.HTML, err = c.renderPost(posts[i].Path)
+			if err != nil {
+				Error(w, r, "failed rendering post: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		// posts are sorted newest first.
+		if i+1 < len(posts) {
+			resp.Prev = newPostRef(posts[i+1])
+		}
+		if i > 0 {
+			resp.Next = newPostRef(posts[i-1])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(resp)
+		if err != nil {
+			Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
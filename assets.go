@@ -0,0 +1,137 @@
+package goblog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// ETags maps file paths within an fs.FS to strong ETags
+// derived from each file's contents.
+type ETags map[string]string
+
+// NewETags walks fsys from root and hashes every regular
+// file it finds.
+func NewETags(fsys fs.FS, root string) (ETags, error) {
+	etags := ETags{}
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		etags[p] = `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return etags, nil
+}
+
+// embedded filesystems never change at runtime, so their
+// ETags and pre-compressed siblings are computed once.
+var (
+	webETags           ETags
+	postsETags         ETags
+	webPrecompressed   Precompressed
+	postsPrecompressed Precompressed
+)
+
+func init() {
+	var err error
+	webETags, err = NewETags(WebFS, "web")
+	if err != nil {
+		panic("could not compute web etags: " + err.Error())
+	}
+	postsETags, err = NewETags(PostsFS, "posts")
+	if err != nil {
+		panic("could not compute posts etags: " + err.Error())
+	}
+	webPrecompressed, err = NewPrecompressed(WebFS, webETags)
+	if err != nil {
+		panic("could not find pre-compressed web assets: " + err.Error())
+	}
+	postsPrecompressed, err = NewPrecompressed(PostsFS, postsETags)
+	if err != nil {
+		panic("could not find pre-compressed posts assets: " + err.Error())
+	}
+}
+
+// errIsDir is returned by openAsset when the requested
+// path is a directory.
+var errIsDir = errors.New("path is a directory")
+
+// openAsset opens the regular file at path p in fsys.
+func openAsset(fsys fs.FS, p string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if fi.IsDir() {
+		f.Close()
+		return nil, nil, &fs.PathError{Op: "open", Path: p, Err: errIsDir}
+	}
+	return f, fi, nil
+}
+
+// serveAsset serves the already opened file f with
+// http.ServeContent, which provides a Content-Type derived
+// from the file's extension, Range requests and conditional
+// GET handling via If-None-Match and If-Modified-Since.
+func serveAsset(w http.ResponseWriter, r *http.Request, f fs.File, fi fs.FileInfo, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		buf, err := io.ReadAll(f)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rs = bytes.NewReader(buf)
+	}
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+}
+
+// serveBytes serves an in-memory representation with the
+// same conditional and Range handling as serveAsset.
+//
+// The provided Content-Type is used as is.
+func serveBytes(w http.ResponseWriter, r *http.Request, contentType, etag string, b []byte) {
+	w.Header().Set("Content-Type", contentType)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+}
+
+// variantETag derives a strong ETag for an alternate
+// representation of the resource tagged with etag.
+func variantETag(etag, variant string) string {
+	if etag == "" {
+		return ""
+	}
+	return etag[:len(etag)-1] + "-" + variant + `"`
+}
@@ -21,9 +21,15 @@ func appPaths(ctx context.Context) {
 	color.Blue(`
 Provide a comma separated list of paths your front-end web application will handle.
 
-Each path should have a leading forward slash.
+Each path should have a leading forward slash and may use the following
+route patterns:
 
-Example: /post,/archive,/settings
+  /about         matches /about exactly
+  /post/:slug    matches a single segment, such as /post/hello-world
+  /archive/*     matches /archive and everything beneath it
+  !/archive/raw  excludes a path, the most specific pattern wins
+
+Example: /post/:slug,/archive/*,/settings,!/archive/raw
 
 `)
 
@@ -35,10 +41,35 @@ Example: /post,/archive,/settings
 	}
 
 	paths := strings.Split(list, ",")
+	if _, err := goblog.ParseAppRoutes(paths); err != nil {
+		color.Red("%v", err)
+		os.Exit(1)
+	}
 	color.Blue("Adding the following paths: %v\n", paths)
 
-	goblog.Conf.AppPaths = paths
-	dest := path.Join(goblog.Home, "src/config/config.yaml")
+	conf := readConfig()
+	conf.AppPaths = paths
+	writeConfig(conf)
+}
+
+// readConfig reads the config.yaml in GoBlog's source,
+// exiting on failure.
+//
+// Changes are made to this config rather than goblog.Conf,
+// which is only the config GoBlog was last built with.
+func readConfig() goblog.Config {
+	conf, err := goblog.DecodeConfig(os.DirFS(goblog.Src))
+	if err != nil {
+		color.Red("Failed to read config: %v", err)
+		os.Exit(1)
+	}
+	return conf
+}
+
+// writeConfig writes conf to the config.yaml in GoBlog's
+// source, exiting on failure.
+func writeConfig(conf goblog.Config) {
+	dest := path.Join(goblog.Src, "config/config.yaml")
 	if _, err := os.Stat(dest); err != nil {
 		color.Red("Could not stat config: %v", err)
 		os.Exit(1)
@@ -49,7 +80,7 @@ Example: /post,/archive,/settings
 		os.Exit(1)
 	}
 	defer f.Close()
-	if err = yaml.NewEncoder(f).Encode(&goblog.Conf); err != nil {
+	if err = yaml.NewEncoder(f).Encode(&conf); err != nil {
 		color.Red("Failed to write config: %v", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+func cors(ctx context.Context) {
+	color.Blue(`
+Configure the cross-origin resource sharing (CORS) policy of your blog.
+
+Press enter to keep the current value shown in brackets or type 'none' to clear it.
+
+`)
+	scanner := bufio.NewScanner(os.Stdin)
+	site := readConfig()
+	conf := site.CORS
+
+	conf.AllowedOrigins = promptList(scanner, `
+Provide a comma separated list of origins allowed to make cross-origin requests.
+
+An origin may contain a single wildcard to match subdomains and '*' allows any origin.
+
+Example: https://example.com,https://*.example.com
+
+`, conf.AllowedOrigins)
+
+	conf.AllowedMethods = promptList(scanner, `
+Provide a comma separated list of http methods cross-origin requests may use.
+
+Example: GET,HEAD,OPTIONS
+
+`, conf.AllowedMethods)
+	for i, m := range conf.AllowedMethods {
+		conf.AllowedMethods[i] = strings.ToUpper(m)
+	}
+
+	conf.AllowedHeaders = promptList(scanner, `
+Provide a comma separated list of headers cross-origin requests may carry.
+
+Example: Content-Type,Authorization
+
+`, conf.AllowedHeaders)
+
+	creds := prompt(scanner, `
+Allow cross-origin requests to include credentials such as cookies? ('yes' or 'no')
+
+`, yesNo(conf.AllowCredentials))
+	conf.AllowCredentials = creds == "yes"
+
+	maxAge := prompt(scanner, `
+How long, in seconds, may browsers cache preflight responses?
+
+`, strconv.Itoa(conf.MaxAge))
+	if maxAge == "" {
+		maxAge = "0"
+	}
+	n, err := strconv.Atoi(maxAge)
+	if err != nil {
+		color.Red("Error: max age must be an integer: %v", err)
+		os.Exit(1)
+	}
+	conf.MaxAge = n
+
+	if err := conf.Validate(); err != nil {
+		color.Red("Error: invalid cors config: %v", err)
+		os.Exit(1)
+	}
+
+	site.CORS = conf
+	writeConfig(site)
+}
+
+// prompt asks question, returning the trimmed answer or
+// current if the answer was empty.
+//
+// Answering 'none' returns an empty string.
+func prompt(scanner *bufio.Scanner, question, current string) string {
+	color.Yellow(question)
+	fmt.Printf("[%s] > ", current)
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		color.Red("Error: failed to scan input: %v", err)
+		os.Exit(1)
+	}
+	answer := strings.TrimSpace(scanner.Text())
+	switch answer {
+	case "":
+		return current
+	case "none":
+		return ""
+	}
+	return answer
+}
+
+// promptList is prompt for comma separated lists.
+func promptList(scanner *bufio.Scanner, question string, current []string) []string {
+	answer := prompt(scanner, question, strings.Join(current, ","))
+	var list []string
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/ldelossa/goblog"
+)
+
+var redirectsUsage = `The 'redirects' subcommand manages the redirects served by your blog.
+
+Usage:
+	goblog config redirects list
+	goblog config redirects add --from PATH --to PATH_OR_URL [--match exact|prefix|pattern] [--code 301|302|308]
+	goblog config redirects remove ID
+
+Match types:
+	exact    redirects requests for exactly --from (the default)
+	prefix   redirects --from and every path beneath it, appending the remainder to --to
+	pattern  --from is a route pattern such as /blog/:year/:slug whose parameters
+	         may be referenced by --to, such as /post/:slug
+`
+
+var redirectsAddFS = flag.NewFlagSet("add", flag.ExitOnError)
+
+var redirectsAddFlags = struct {
+	from  *string
+	to    *string
+	match *string
+	code  *int
+}{
+	from:  redirectsAddFS.String("from", "", "the path to redirect"),
+	to:    redirectsAddFS.String("to", "", "the path or absolute URL to redirect to"),
+	match: redirectsAddFS.String("match", goblog.RedirectExact, "how --from is matched, one of 'exact', 'prefix' or 'pattern'"),
+	code:  redirectsAddFS.Int("code", 301, "the http status code of the redirect, one of 301, 302 or 308"),
+}
+
+func redirects(ctx context.Context) {
+	// 0: goblog, 1: config, 2: redirects, 3: directive
+	if len(os.Args) < 4 {
+		color.Red("Error: The 'redirects' subcommand requires a directive.\n")
+		color.Blue(redirectsUsage)
+		os.Exit(1)
+	}
+	switch os.Args[3] {
+	case "list":
+		listRedirects()
+	case "add":
+		addRedirect()
+	case "remove":
+		removeRedirect()
+	case "--help", "-help":
+		fmt.Print(redirectsUsage)
+	default:
+		color.Red("Error: unknown directive %q.\n", os.Args[3])
+		color.Blue(redirectsUsage)
+		os.Exit(1)
+	}
+}
+
+func listRedirects() {
+	conf := readConfig()
+	if len(conf.Redirects) == 0 {
+		fmt.Println("No redirects configured.")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "ID\tFROM\tTO\tMATCH\tCODE")
+	for i, rd := range conf.Redirects {
+		match, code := rd.Match, rd.Code
+		if match == "" {
+			match = goblog.RedirectExact
+		}
+		if code == 0 {
+			code = 301
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\n", i+1, rd.From, rd.To, match, code)
+	}
+	if err := tw.Flush(); err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+func addRedirect() {
+	redirectsAddFS.Usage = func() {
+		fmt.Print(redirectsUsage)
+	}
+	redirectsAddFS.Parse(os.Args[4:])
+
+	rd := goblog.Redirect{
+		From:  *redirectsAddFlags.from,
+		To:    *redirectsAddFlags.to,
+		Match: *redirectsAddFlags.match,
+		Code:  *redirectsAddFlags.code,
+	}
+	conf := readConfig()
+	conf.Redirects = append(conf.Redirects, rd)
+	saveRedirects(conf)
+	color.Blue("Added redirect from %v to %v\n", rd.From, rd.To)
+}
+
+func removeRedirect() {
+	if len(os.Args) < 5 {
+		color.Red("Error: The 'remove' directive requires the ID of a redirect, see 'goblog config redirects list'.\n")
+		os.Exit(1)
+	}
+	conf := readConfig()
+	id, err := strconv.Atoi(os.Args[4])
+	if err != nil || id < 1 || id > len(conf.Redirects) {
+		color.Red("Error: %q is not a valid redirect ID.\n", os.Args[4])
+		os.Exit(1)
+	}
+	rd := conf.Redirects[id-1]
+	rules := append([]goblog.Redirect{}, conf.Redirects[:id-1]...)
+	conf.Redirects = append(rules, conf.Redirects[id:]...)
+	saveRedirects(conf)
+	color.Blue("Removed redirect from %v to %v\n", rd.From, rd.To)
+}
+
+// saveRedirects validates the redirects of conf against
+// each other and the aliases of posts, scheduled ones
+// included, before writing conf.
+func saveRedirects(conf goblog.Config) {
+	if _, err := goblog.NewRedirects(conf.Redirects, goblog.DSCache); err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+	writeConfig(conf)
+}
@@ -14,6 +14,8 @@ These configs are embedded into the GoBlog binary.
 If you're changing a config option you'll need to rebuild GoBlog.
 
 goblog config app-paths  - specify your web applicatoin's
+goblog config cors       - configure the cross-origin resource sharing policy
+goblog config redirects  - add, list and remove redirects
 goblog config fork       - update your goblog fork
 `
 
@@ -31,6 +33,10 @@ func Root(ctx context.Context) {
 	switch os.Args[2] {
 	case "app-paths":
 		appPaths(ctx)
+	case "cors":
+		cors(ctx)
+	case "redirects":
+		redirects(ctx)
 	case "fork":
 	}
 }
@@ -93,6 +93,38 @@ Hero images live in the /posts directory so supply a path such as:
 	}
 	draft.Hero = scanner.Text()
 
+	// category prompt
+	color.Yellow(`
+What category does this post belong to? (optional)
+
+`)
+	fmt.Printf("> ")
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		color.Red("Error: something went wrong inputing your category: %v", err)
+		os.Exit(1)
+	}
+	draft.Category = strings.TrimSpace(scanner.Text())
+
+	// tags prompt
+	color.Yellow(`
+Provide a comma separated list of tags for this post. (optional)
+
+Example: go,http,embed
+
+`)
+	fmt.Printf("> ")
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		color.Red("Error: something went wrong inputing your tags: %v", err)
+		os.Exit(1)
+	}
+	for _, tag := range strings.Split(scanner.Text(), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			draft.Tags = append(draft.Tags, tag)
+		}
+	}
+
 	if _, err := os.Stat(goblog.Drafts); os.IsNotExist(err) {
 		err := os.Mkdir(goblog.Drafts, 0770)
 		if err != nil {
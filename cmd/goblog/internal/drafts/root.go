@@ -23,6 +23,7 @@ goblog drafts list    - list drafts
 goblog drafts view    - view the contents of a draft
 goblog drafts delete  - delete a draft
 goblog drafts publish - publishes a draft 
+goblog drafts schedule - publishes a draft which stays hidden until a given time
 `
 
 // Root is the 'drafts' subcommand root handler.
@@ -50,6 +51,8 @@ func Root(ctx context.Context) {
 		delete(ctx)
 	case "publish":
 		publish(ctx)
+	case "schedule":
+		schedule(ctx)
 	default:
 		color.Red(`
 Error: unknown subcommand provided.
@@ -0,0 +1,144 @@
+package drafts
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ldelossa/goblog"
+	"gopkg.in/yaml.v3"
+)
+
+var scheduleFS = flag.NewFlagSet("schedule", flag.ExitOnError)
+
+var scheduleFlags = struct {
+	at *string
+}{
+	at: scheduleFS.String("at", "", "when the post should be published, as RFC 3339 or 'YYYY-MM-DD HH:MM' in local time"),
+}
+
+// scheduleLayouts are the time formats accepted by the
+// '--at' flag, local time is assumed when a layout has no
+// zone.
+var scheduleLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func schedule(ctx context.Context) {
+	scheduleFS.Usage = func() {
+		fmt.Printf(`
+The schedule subcommand publishes an existing draft which remains hidden until the provided time.
+
+The post is dated with its publish time and will be embedded into the next GoBlog binary created by running 'goblog publish'.
+A running blog reveals the post once the time passes, no restart is required.
+
+Usage:
+	goblog drafts schedule ID --at <time>
+
+Example:
+	goblog drafts schedule 2 --at "2021-06-01 09:00"
+	goblog drafts schedule 2 --at 2021-06-01T09:00:00Z
+
+`)
+	}
+
+	// 0: goblog, 1: drafts, 2: schedule, 3: ID
+	if len(os.Args) < 4 {
+		color.Red("Error: Not enough arguments provided to 'schedule' subcommand\n")
+		scheduleFS.Usage()
+		os.Exit(1)
+	}
+	scheduleFS.Parse(os.Args[4:])
+
+	// first arg must be id
+	id, err := strconv.Atoi(os.Args[3])
+	if err != nil {
+		color.Red("Error: first argument to 'schedule' subcommand must be an integer id")
+		os.Exit(1)
+	}
+
+	if *scheduleFlags.at == "" {
+		color.Red("Error: the '--at' flag is required")
+		scheduleFS.Usage()
+		os.Exit(1)
+	}
+	var at time.Time
+	for _, layout := range scheduleLayouts {
+		at, err = time.ParseInLocation(layout, *scheduleFlags.at, time.Local)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		color.Red("Error: could not parse %q as a time, use RFC 3339 or 'YYYY-MM-DD HH:MM'", *scheduleFlags.at)
+		os.Exit(1)
+	}
+	if !at.After(time.Now()) {
+		color.Yellow("Warning: %v has already passed, the post will be visible immediately.", at.Format(time.RFC1123))
+	}
+
+	sorted, err := sortedDrafts(ctx)
+	if err != nil {
+		color.Red("Error: failed retrieving drafts: %v", err)
+		os.Exit(1)
+	}
+	if len(sorted) == 0 {
+		color.Blue("There are no drafts to schedule currently.\nUse 'goblog drafts new' to create one.")
+		os.Exit(0)
+	}
+
+	if id < 1 || id > len(sorted) {
+		color.Red("Error: draft id %d does not exist, see 'goblog drafts list'", id)
+		scheduleFS.Usage()
+		os.Exit(1)
+	}
+
+	draft := sorted[id-1]
+	draft.PublishAt = at
+	draft.Date = at
+
+	base := filepath.Base(draft.Path)
+	postPath := path.Join(goblog.Posts, base)
+	f, err := os.OpenFile(postPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o0660)
+	if err != nil {
+		color.Red("Error: failed to create GoBlog post file: %v", err)
+		os.Exit(1)
+	}
+	err = yaml.NewEncoder(f).Encode(draft)
+	if err != nil {
+		f.Close()
+		os.Remove(postPath)
+		color.Red("Error: failed to write GoBlog post file: %v", err)
+		os.Exit(1)
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(postPath)
+		color.Red("Error: failed to write GoBlog post file: %v", err)
+		os.Exit(1)
+	}
+
+	err = os.Remove(path.Join(goblog.Drafts, base))
+	if err != nil {
+		color.Red(`
+Error: failed to remove draft: %v
+
+Your post was still scheduled but will remain viewable in the draft list.
+
+`, err)
+		os.Exit(1)
+	}
+
+	color.Blue(`
+Your draft has been scheduled for %v and written to: %v.
+
+`, at.Format(time.RFC1123), postPath)
+}
@@ -0,0 +1,88 @@
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/ldelossa/goblog"
+	"github.com/ldelossa/goblog/pkg/dtree"
+)
+
+// compressMinSize is the smallest file worth
+// pre-compressing.
+const compressMinSize = 1024
+
+// NewCompressDecision returns a Decision which writes
+// pre-compressed siblings (such as "app.js.gz" and "app.js.br")
+// of every compressible file in the web root and posts directory
+// of GoBlog's source.
+//
+// The siblings are embedded into the next GoBlog binary and
+// served to clients which accept their encoding.
+//
+// This decision always calls its Yes branch or errors.
+func NewCompressDecision() *dtree.Decision {
+	return &dtree.Decision{
+		Exec: func(ctx context.Context) (bool, error) {
+			for _, dir := range []string{path.Join(goblog.Src, "web"), goblog.Posts} {
+				color.Blue("Compressing assets in %v\n", dir)
+				if err := compressDir(dir); err != nil {
+					return false, fmt.Errorf("Failed compressing assets in %v: %w", dir, err)
+				}
+			}
+			return true, nil
+		},
+	}
+}
+
+func compressDir(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isSibling(p) {
+			return nil
+		}
+		if !goblog.Compressible(mime.TypeByExtension(filepath.Ext(p))) {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for _, enc := range goblog.Encodings {
+			sibling := p + enc.Ext
+			if len(b) < compressMinSize {
+				// remove any sibling left over from when
+				// the file was larger.
+				os.Remove(sibling)
+				continue
+			}
+			compressed, err := goblog.Compress(enc.Name, b)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(sibling, compressed, 0o660); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isSibling reports whether p is itself a pre-compressed
+// sibling.
+func isSibling(p string) bool {
+	for _, enc := range goblog.Encodings {
+		if filepath.Ext(p) == enc.Ext {
+			return true
+		}
+	}
+	return false
+}
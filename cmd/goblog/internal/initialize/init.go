@@ -251,21 +251,9 @@ func NewCheckGitRepoDecision() *dtree.Decision {
 func NewBuildDecision() *dtree.Decision {
 	return &dtree.Decision{
 		Exec: func(ctx context.Context) (bool, error) {
-			// bump the build version
-			goblog.Conf.BuildNum++
-
-			// write out the new config
-			dest := path.Join(goblog.Home, "src/config/config.yaml")
-			if _, err := os.Stat(dest); err != nil {
-				return false, fmt.Errorf("Could not stat config: %v", err)
-			}
-			f, err := os.OpenFile(dest, os.O_RDWR|os.O_TRUNC, 0)
+			dest, err := bumpBuildNum(goblog.Src)
 			if err != nil {
-				return false, fmt.Errorf("Failed to open config.yaml: %v", err)
-			}
-			defer f.Close()
-			if err = yaml.NewEncoder(f).Encode(&goblog.Conf); err != nil {
-				return false, fmt.Errorf("Failed to write config: %v", err)
+				return false, err
 			}
 			color.Blue(`
 Wrote new config to %v
@@ -293,9 +281,17 @@ Wrote new config to %v
 Building your new GoBlog binary @ %v
 
 `, buildDest)
+			// stamp the binary with the commit it was built from,
+			// see goblog.Commit.
+			args := []string{"go", "build"}
+			if repo, err := git.PlainOpen(goblog.Src); err == nil {
+				if head, err := repo.Head(); err == nil {
+					args = append(args, "-ldflags", "-X github.com/ldelossa/goblog.Commit="+head.Hash().String())
+				}
+			}
 			goBuild := exec.Cmd{
 				Path:   goPath,
-				Args:   []string{"go", "build", "-o", "../bin/goblog", "./cmd/goblog"},
+				Args:   append(args, "-o", "../bin/goblog", "./cmd/goblog"),
 				Dir:    path.Join(goblog.Home, "src"),
 				Stdin:  os.Stdin,
 				Stdout: os.Stdout,
@@ -320,6 +316,30 @@ If things look off, git reset your GoBlog's src directory and your current binar
 	}
 }
 
+// bumpBuildNum increments the build number of the config
+// in the GoBlog source at src, returning the config's path.
+//
+// The source config is read rather than goblog.Conf so edits
+// made since the running binary was built are kept.
+func bumpBuildNum(src string) (string, error) {
+	conf, err := goblog.DecodeConfig(os.DirFS(src))
+	if err != nil {
+		return "", err
+	}
+	conf.BuildNum++
+
+	dest := path.Join(src, "config/config.yaml")
+	f, err := os.OpenFile(dest, os.O_RDWR|os.O_TRUNC, 0)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open config.yaml: %v", err)
+	}
+	defer f.Close()
+	if err = yaml.NewEncoder(f).Encode(&conf); err != nil {
+		return "", fmt.Errorf("Failed to write config: %v", err)
+	}
+	return dest, nil
+}
+
 // NewBuildNumDecision returns a Decision which
 // returns an error if the embedded config build number
 // does not match the build number in the goblog's home.
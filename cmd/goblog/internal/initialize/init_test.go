@@ -0,0 +1,46 @@
+package initialize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ldelossa/goblog"
+)
+
+func TestBumpBuildNum(t *testing.T) {
+	src := t.TempDir()
+	if err := os.Mkdir(filepath.Join(src, "config"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	// an edit made with the config subcommands since the
+	// running binary was built.
+	conf := "buildnum: 4\nbase_url: https://blog.example.com\napppaths:\n  - /post/:slug\n"
+	if err := os.WriteFile(filepath.Join(src, "config/config.yaml"), []byte(conf), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bumpBuildNum(src); err != nil {
+		t.Fatalf("bumpBuildNum: %v", err)
+	}
+
+	got, err := goblog.DecodeConfig(os.DirFS(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BuildNum != 5 {
+		t.Errorf("got BuildNum %d, want 5", got.BuildNum)
+	}
+	if got.BaseURL != "https://blog.example.com" {
+		t.Errorf("got BaseURL %q, the edit was lost", got.BaseURL)
+	}
+	if len(got.AppPaths) != 1 || got.AppPaths[0] != "/post/:slug" {
+		t.Errorf("got AppPaths %v, the edit was lost", got.AppPaths)
+	}
+}
+
+func TestBumpBuildNumMissingConfig(t *testing.T) {
+	if _, err := bumpBuildNum(t.TempDir()); err == nil {
+		t.Error("expected an error for a source without a config")
+	}
+}
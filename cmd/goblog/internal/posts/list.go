@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/fatih/color"
@@ -14,7 +15,10 @@ import (
 var listFS = flag.NewFlagSet("list", flag.ExitOnError)
 
 var listFlags = struct {
-}{}
+	tag *string
+}{
+	tag: listFS.String("tag", "", "only list posts carrying this tag"),
+}
 
 func list(ctx context.Context, local bool) {
 	listFS.Usage = func() {
@@ -23,7 +27,10 @@ The 'list' subcommand will list posts in date order.
 
 If the '--local' flag is used a list of local posts, ones not emedded into the binary, will be listed.
 
-This subcommand takes no arguments.
+The '--tag' flag may be used to only list posts carrying the provided tag.
+
+Usage:
+	goblog posts list [--tag TAG]
 `)
 	}
 
@@ -47,9 +54,14 @@ This subcommand takes no arguments.
 	}
 
 	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
-	fmt.Fprintln(tw, "ID\tDATE\tTITLE\tSUMMARY")
+	fmt.Fprintln(tw, "ID\tDATE\tTITLE\tSUMMARY\tTAGS")
 	for i, post := range posts {
-		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", i+1, post.Date.Format("2006-Jan-2"), post.Title, post.Summary)
+		// ids are positions in the unfiltered list so they
+		// remain valid for the other 'posts' subcommands.
+		if *listFlags.tag != "" && !post.HasTag(*listFlags.tag) {
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, post.Date.Format("2006-Jan-2"), post.Title, post.Summary, strings.Join(post.Tags, ","))
 	}
 	err = tw.Flush()
 	if err != nil {
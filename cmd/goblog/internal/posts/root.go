@@ -20,7 +20,7 @@ Usage:
 
 goblog posts [--local] subcommand
 
-goblog posts list  - list published blog posts and their id
+goblog posts list  - list published blog posts and their id (--tag to filter)
 goblog posts view  - view the markdown contents of a post
 goblog posts draft - unpublish a post and move it to draft (assumes --local flag)
 `
@@ -0,0 +1,97 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ldelossa/goblog"
+	"github.com/ldelossa/goblog/pkg/metrics"
+)
+
+// access log formats accepted by the '-access-log' flag.
+const (
+	accessLogOff    = "off"
+	accessLogCommon = "common"
+	accessLogJSON   = "json"
+)
+
+// accessEntry is a single access log line.
+type accessEntry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	Duration   float64   `json:"duration_ms"`
+	RemoteAddr string    `json:"remote_addr"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// accessLog wraps h, writing an entry to out for every request
+// in the provided format.
+//
+// The common format is the Apache combined log format with the
+// request duration, in milliseconds, appended.
+func accessLog(format string, out io.Writer, proxies trustedProxies, h http.Handler) (http.Handler, error) {
+	var mu sync.Mutex
+	var write func(accessEntry)
+	switch format {
+	case accessLogOff:
+		return h, nil
+	case accessLogCommon:
+		write = func(e accessEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(out, "%s - - [%s] %q %d %d %q %q %.3f\n",
+				e.RemoteAddr,
+				e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+				e.Method+" "+e.Path+" "+e.Proto,
+				e.Status,
+				e.Bytes,
+				e.Referer,
+				e.UserAgent,
+				e.Duration,
+			)
+		}
+	case accessLogJSON:
+		enc := json.NewEncoder(out)
+		write = func(e accessEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			enc.Encode(e)
+		}
+	default:
+		return nil, fmt.Errorf("unknown access log format %q, must be one of %q, %q or %q",
+			format, accessLogOff, accessLogCommon, accessLogJSON)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rr := &metrics.ResponseRecorder{ResponseWriter: w}
+		h.ServeHTTP(rr, r)
+		status := rr.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		write(accessEntry{
+			Time:       start,
+			Method:     r.Method,
+			Path:       r.URL.RequestURI(),
+			Proto:      r.Proto,
+			Status:     status,
+			Bytes:      rr.Bytes,
+			Duration:   float64(time.Since(start).Microseconds()) / 1000,
+			RemoteAddr: proxies.clientIP(r),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+			RequestID:  goblog.RequestID(r.Context()),
+		})
+	}), nil
+}
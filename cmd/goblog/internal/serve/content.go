@@ -0,0 +1,126 @@
+package serve
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/ldelossa/goblog"
+)
+
+// swapHandler is an http.Handler which may be replaced
+// while serving.
+type swapHandler struct {
+	v atomic.Value
+}
+
+// handlerBox gives every value stored in swapHandler the
+// same concrete type, as atomic.Value requires.
+type handlerBox struct {
+	http.Handler
+}
+
+func (s *swapHandler) store(h http.Handler) {
+	s.v.Store(handlerBox{h})
+}
+
+func (s *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.v.Load().(handlerBox).ServeHTTP(w, r)
+}
+
+// contentDirs are the directories of a goblog source tree
+// watched for changes.
+var contentDirs = []string{"posts", "web", "config", "templates"}
+
+// contentFingerprint summarizes the path, size and
+// modification time of every file in dirs of root.
+func contentFingerprint(root string, dirs []string) (string, error) {
+	h := sha256.New()
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				// directories may be missing or come and go
+				// while walking.
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			fi, err := d.Info()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			fmt.Fprintf(h, "%s %d %d %v\n", p, fi.Size(), fi.ModTime().UnixNano(), fi.IsDir())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// watchContent polls dirs of root every interval, calling
+// reload when their content changes, until ctx is done.
+func watchContent(ctx context.Context, root string, dirs []string, interval time.Duration, reload func()) {
+	last, err := contentFingerprint(root, dirs)
+	if err != nil {
+		log.Printf("Failed checking %v for changes: %v\n", root, err)
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		fp, err := contentFingerprint(root, dirs)
+		if err != nil {
+			log.Printf("Failed checking %v for changes: %v\n", root, err)
+			continue
+		}
+		if fp == last {
+			continue
+		}
+		last = fp
+		reload()
+	}
+}
+
+// liveReloadHandler serves reload's event stream at
+// goblog.LiveReloadPath, passing every other request to h.
+func liveReloadHandler(reload *goblog.LiveReload, h http.Handler) http.Handler {
+	events := reload.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == goblog.LiveReloadPath {
+			events.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// flagSet reports whether the serve flag name was provided
+// on the command line.
+func flagSet(name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,27 @@
+package serve
+
+import (
+	"fmt"
+
+	"github.com/ldelossa/goblog"
+	"github.com/rs/cors"
+)
+
+// newCORS builds the cors middleware for the configured
+// policy, falling back to cors.Default when none was
+// configured.
+func newCORS(c goblog.CORS) (*cors.Cors, error) {
+	if c.IsZero() {
+		return cors.Default(), nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cors config: %w", err)
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}), nil
+}
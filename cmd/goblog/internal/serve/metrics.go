@@ -0,0 +1,79 @@
+package serve
+
+import (
+	"net/http"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ldelossa/goblog"
+	"github.com/ldelossa/goblog/pkg/metrics"
+)
+
+// serveMetrics are the metrics exposed by 'goblog serve'
+// when the '-metrics' flag is provided.
+//
+// A nil *serveMetrics disables instrumentation.
+type serveMetrics struct {
+	registry metrics.Registry
+	http     *metrics.HTTPMetrics
+	postHits *metrics.CounterVec
+}
+
+func newServeMetrics() *serveMetrics {
+	m := &serveMetrics{}
+	m.http = m.registry.NewHTTPMetrics("goblog")
+	m.postHits = m.registry.NewCounterVec("goblog_post_hits_total",
+		"Total number of successful requests for each post.", "post")
+	m.registry.NewGaugeFunc("goblog_build_info",
+		"Build information of the running goblog binary.",
+		map[string]string{
+			"build_num":  strconv.FormatInt(goblog.Conf.BuildNum, 10),
+			"go_version": runtime.Version(),
+		},
+		func() float64 { return 1 },
+	)
+	m.registry.NewGaugeFunc("goblog_posts",
+		"Number of posts being served.",
+		nil,
+		func() float64 { return float64(len(goblog.Published())) },
+	)
+	return m
+}
+
+// instrument wraps h, recording its requests under the
+// provided handler name.
+func (m *serveMetrics) instrument(name string, h http.Handler) http.Handler {
+	if m == nil {
+		return h
+	}
+	return m.http.Instrument(name, h, m.observePost)
+}
+
+// observePost counts successful requests for posts, whether
+// addressed by path or by slug.
+func (m *serveMetrics) observePost(r *http.Request, rr *metrics.ResponseRecorder) {
+	if rr.Status != 0 && rr.Status != http.StatusOK {
+		return
+	}
+	var slug string
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/posts/"):
+		slug = strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/posts/"), "/")
+	case strings.HasPrefix(r.URL.Path, "/posts/") && path.Ext(r.URL.Path) == ".post":
+		slug = goblog.SlugFromPath(r.URL.Path)
+	default:
+		return
+	}
+	// only count known posts to bound the label's cardinality.
+	if _, ok := goblog.Published().BySlug(slug); ok {
+		m.postHits.Inc(slug)
+	}
+}
+
+// handler serves the metrics in the Prometheus text
+// exposition format.
+func (m *serveMetrics) handler() http.Handler {
+	return m.registry.Handler()
+}
@@ -0,0 +1,88 @@
+package serve
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies is a set of networks whose X-Forwarded-For
+// headers are believed.
+type trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses a comma separated list of
+// IP addresses and CIDR networks.
+func parseTrustedProxies(list string) (trustedProxies, error) {
+	var tp trustedProxies
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address: %v", entry)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			tp = append(tp, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network: %w", err)
+		}
+		tp = append(tp, n)
+	}
+	return tp, nil
+}
+
+func (tp trustedProxies) trusted(ip net.IP) bool {
+	for _, n := range tp {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP determines the address of the client which
+// made the request.
+//
+// When the request arrives from a trusted proxy the
+// X-Forwarded-For header is walked from the right, the
+// first address which is not itself a trusted proxy is
+// the client.
+func (tp trustedProxies) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !tp.trusted(ip) {
+		return host
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		hopIP := net.ParseIP(hop)
+		if hopIP == nil {
+			// a malformed entry can't be trusted, stop at
+			// the last address we know is good.
+			break
+		}
+		host = hop
+		if !tp.trusted(hopIP) {
+			break
+		}
+	}
+	return host
+}
@@ -0,0 +1,173 @@
+package serve
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ldelossa/goblog"
+)
+
+// rateRule limits clients requesting paths beginning with
+// prefix to rate requests per second, allowing bursts of
+// up to burst requests.
+type rateRule struct {
+	prefix string
+	rate   float64
+	burst  int
+}
+
+// parseRateLimits parses a comma separated list of
+// "<prefix>=<rate>[:<burst>]" rules, where rate is the
+// number of requests per second a single client may make.
+//
+// When burst is omitted it defaults to the rate rounded up.
+func parseRateLimits(list string) ([]rateRule, error) {
+	var rules []rateRule
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: expected <prefix>=<rate>[:<burst>]", entry)
+		}
+		rule := rateRule{prefix: entry[:i]}
+		if !strings.HasPrefix(rule.prefix, "/") {
+			return nil, fmt.Errorf("invalid rate limit %q: prefix must begin with '/'", entry)
+		}
+
+		rate, burst := entry[i+1:], ""
+		if j := strings.Index(rate, ":"); j >= 0 {
+			rate, burst = rate[:j], rate[j+1:]
+		}
+		var err error
+		rule.rate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || rule.rate <= 0 || math.IsInf(rule.rate, 0) {
+			return nil, fmt.Errorf("invalid rate limit %q: rate must be a positive number", entry)
+		}
+		rule.burst = int(math.Ceil(rule.rate))
+		if burst != "" {
+			rule.burst, err = strconv.Atoi(burst)
+			if err != nil || rule.burst <= 0 {
+				return nil, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", entry)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// bucket is a single client's token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter holds a token bucket per client for a rateRule.
+type limiter struct {
+	rule rateRule
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// sweepInterval is how often buckets which have refilled
+// are forgotten.
+const sweepInterval = time.Minute
+
+// allow takes a token from client's bucket, reporting how
+// long the client must wait when none are available.
+func (l *limiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.rule.burst), last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(float64(l.rule.burst), b.tokens+now.Sub(b.last).Seconds()*l.rule.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rule.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets buckets which would be full by now, they
+// are indistinguishable from a new client.
+func (l *limiter) sweep(now time.Time) {
+	full := time.Duration(float64(l.rule.burst) / l.rule.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, client)
+		}
+	}
+	l.swept = now
+}
+
+// rateLimiter holds a limiter for each of a set of rules.
+//
+// It outlives the handlers built by rateLimit, so clients'
+// buckets survive rebuilding them.
+type rateLimiter struct {
+	limiters []*limiter
+}
+
+func newRateLimiter(rules []rateRule) *rateLimiter {
+	rl := &rateLimiter{limiters: make([]*limiter, len(rules))}
+	for i, rule := range rules {
+		rl.limiters[i] = &limiter{rule: rule, buckets: map[string]*bucket{}}
+	}
+	return rl
+}
+
+// rateLimit wraps h, limiting the rate at which each client
+// may request paths matching the rules of rl. When several
+// rules match a path the longest prefix wins.
+//
+// Clients are identified by their address as determined by
+// proxies. Requests over the limit receive a 429 with a
+// Retry-After header.
+func (rl *rateLimiter) rateLimit(proxies trustedProxies, h http.Handler) http.Handler {
+	if len(rl.limiters) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var l *limiter
+		for _, cand := range rl.limiters {
+			if !strings.HasPrefix(r.URL.Path, cand.rule.prefix) {
+				continue
+			}
+			if l == nil || len(cand.rule.prefix) > len(l.rule.prefix) {
+				l = cand
+			}
+		}
+		if l == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		ok, wait := l.allow(proxies.clientIP(r), time.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			goblog.Error(w, r, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
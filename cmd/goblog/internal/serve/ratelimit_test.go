@@ -0,0 +1,164 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tt := []struct {
+		name   string
+		list   string
+		want   []rateRule
+		hasErr bool
+	}{
+		{name: "empty", list: ""},
+		{name: "default burst", list: "/search=2.5", want: []rateRule{{prefix: "/search", rate: 2.5, burst: 3}}},
+		{name: "burst", list: "/search=1:5", want: []rateRule{{prefix: "/search", rate: 1, burst: 5}}},
+		{name: "several", list: " /a=1 , /b=2:4,", want: []rateRule{{prefix: "/a", rate: 1, burst: 1}, {prefix: "/b", rate: 2, burst: 4}}},
+		{name: "prefix with equals", list: "/a=b=1", want: []rateRule{{prefix: "/a=b", rate: 1, burst: 1}}},
+		{name: "relative prefix", list: "search=1", hasErr: true},
+		{name: "no prefix", list: "=1", hasErr: true},
+		{name: "no rate", list: "/search", hasErr: true},
+		{name: "zero rate", list: "/search=0", hasErr: true},
+		{name: "negative rate", list: "/search=-1", hasErr: true},
+		{name: "infinite rate", list: "/search=Inf", hasErr: true},
+		{name: "zero burst", list: "/search=1:0", hasErr: true},
+		{name: "fractional burst", list: "/search=1:1.5", hasErr: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseRateLimits(tc.list)
+			if (err != nil) != tc.hasErr {
+				t.Fatalf("got error %v, want error %v", err, tc.hasErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	start := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+	type req struct {
+		// after is the time since start the request is made.
+		after    time.Duration
+		client   string
+		allowed  bool
+		wantWait time.Duration
+	}
+	tt := []struct {
+		name string
+		rule rateRule
+		reqs []req
+	}{
+		{
+			name: "burst then limited",
+			rule: rateRule{rate: 1, burst: 3},
+			reqs: []req{
+				{client: "a", allowed: true},
+				{client: "a", allowed: true},
+				{client: "a", allowed: true},
+				{client: "a", wantWait: time.Second},
+			},
+		},
+		{
+			name: "refills at rate",
+			rule: rateRule{rate: 2, burst: 1},
+			reqs: []req{
+				{client: "a", allowed: true},
+				{after: 250 * time.Millisecond, client: "a", wantWait: 250 * time.Millisecond},
+				{after: 500 * time.Millisecond, client: "a", allowed: true},
+				{after: 500 * time.Millisecond, client: "a", wantWait: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refill capped at burst",
+			rule: rateRule{rate: 10, burst: 2},
+			reqs: []req{
+				{client: "a", allowed: true},
+				{after: time.Hour, client: "a", allowed: true},
+				{after: time.Hour, client: "a", allowed: true},
+				{after: time.Hour, client: "a", wantWait: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "clients are independent",
+			rule: rateRule{rate: 1, burst: 1},
+			reqs: []req{
+				{client: "a", allowed: true},
+				{client: "a", wantWait: time.Second},
+				{client: "b", allowed: true},
+			},
+		},
+		{
+			name: "swept buckets start full",
+			rule: rateRule{rate: 1, burst: 2},
+			reqs: []req{
+				{client: "a", allowed: true},
+				{client: "a", allowed: true},
+				{after: 2 * sweepInterval, client: "b", allowed: true},
+				{after: 2 * sweepInterval, client: "a", allowed: true},
+				{after: 2 * sweepInterval, client: "a", allowed: true},
+				{after: 2 * sweepInterval, client: "a", wantWait: time.Second},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newRateLimiter([]rateRule{tc.rule}).limiters[0]
+			for i, r := range tc.reqs {
+				ok, wait := l.allow(r.client, start.Add(r.after))
+				if ok != r.allowed || wait != r.wantWait {
+					t.Fatalf("request %d: got %v, %v, want %v, %v", i, ok, wait, r.allowed, r.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitLongestPrefix(t *testing.T) {
+	rl := newRateLimiter([]rateRule{
+		{prefix: "/", rate: 1, burst: 100},
+		{prefix: "/search", rate: 1, burst: 1},
+	})
+	h := rl.rateLimit(trustedProxies{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tt := []struct {
+		path string
+		want int
+	}{
+		{path: "/search", want: http.StatusOK},
+		{path: "/search", want: http.StatusTooManyRequests},
+		{path: "/search/more", want: http.StatusTooManyRequests},
+		{path: "/posts", want: http.StatusOK},
+	}
+	for _, tc := range tt {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%v: got status %d, want %d", tc.path, rec.Code, tc.want)
+		}
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("%v: got Retry-After %q, want 1", tc.path, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestRateLimitSurvivesRebuild(t *testing.T) {
+	rl := newRateLimiter([]rateRule{{prefix: "/", rate: 1, burst: 1}})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// handlers are rebuilt whenever content is reloaded.
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		rl.rateLimit(trustedProxies{}, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != want {
+			t.Errorf("request %d: got status %d, want %d", i, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package serve
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// rotatingFile is an io.Writer appending to a file which is
+// rotated once it grows beyond maxSize bytes.
+//
+// Rotated files are renamed with a numeric suffix, "access.log.1"
+// being the most recent, and at most backups are kept.
+//
+// A zero maxSize disables rotation. When rotating fails the
+// file keeps growing and rotation is retried after
+// rotateRetry.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+	// failed is when rotating last failed.
+	failed time.Time
+}
+
+// rotateRetry is how long rotatingFile waits to rotate again
+// after failing to.
+const rotateRetry = time.Minute
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size = f, fi.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(b []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(b)) > rf.maxSize && time.Since(rf.failed) > rotateRetry {
+		if err := rf.rotate(); err != nil {
+			rf.failed = time.Now()
+			log.Printf("Failed rotating %v: %v\n", rf.path, err)
+			if rf.f == nil {
+				return 0, fmt.Errorf("failed rotating %v: %w", rf.path, err)
+			}
+		}
+	}
+	n, err := rf.f.Write(b)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file aside, shifting older
+// backups, and opens a new one. When the file can't be moved
+// aside it is reopened for appending, so writes continue.
+func (rf *rotatingFile) rotate() error {
+	rf.f.Close()
+	rf.f = nil
+
+	var err error
+	if rf.backups > 0 {
+		for i := rf.backups - 1; i > 0; i-- {
+			from, to := fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1)
+			if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed shifting %v to %v: %v\n", from, to, err)
+			}
+		}
+		err = os.Rename(rf.path, rf.path+".1")
+	} else {
+		err = os.Remove(rf.path)
+	}
+	if oerr := rf.open(); oerr != nil && err == nil {
+		err = oerr
+	}
+	return err
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	return rf.f.Close()
+}
@@ -2,23 +2,70 @@ package serve
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/ldelossa/goblog"
-	"github.com/rs/cors"
 )
 
 var fs = flag.NewFlagSet("serve", flag.ExitOnError)
 
 var flags = struct {
-	listenAddr *string
+	listenAddr       *string
+	metrics          *bool
+	accessLog        *string
+	accessLogFile    *string
+	accessLogMaxSize *int64
+	accessLogBackups *int
+	trustedProxies   *string
+	tlsCert          *string
+	tlsKey           *string
+	selfSigned       *bool
+	httpRedirect     *string
+	hstsMaxAge       *time.Duration
+	hstsSubdomains   *bool
+	hstsPreload      *bool
+	rateLimits       *string
+	readTimeout      *time.Duration
+	writeTimeout     *time.Duration
+	idleTimeout      *time.Duration
+	maxHeaderBytes   *int
+	theme            *bool
+	contentDir       *string
+	contentPoll      *time.Duration
+	liveReload       *bool
 }{
-	listenAddr: fs.String("l", "localhost:8080", "a <host:port> string where goblog will listen for http requests"),
+	listenAddr:       fs.String("l", "localhost:8080", "a <host:port> string where goblog will listen for http requests"),
+	metrics:          fs.Bool("metrics", false, "expose prometheus metrics at /metrics"),
+	accessLog:        fs.String("access-log", accessLogCommon, "access log format, one of 'common', 'json' or 'off'"),
+	accessLogFile:    fs.String("access-log-file", "", "write access logs to this file instead of stdout"),
+	accessLogMaxSize: fs.Int64("access-log-max-size", 0, "rotate the access log file once it grows beyond this many megabytes, 0 disables rotation"),
+	accessLogBackups: fs.Int("access-log-backups", 3, "the number of rotated access log files to keep"),
+	trustedProxies:   fs.String("trusted-proxies", "", "a comma separated list of proxy IPs or CIDRs whose X-Forwarded-For headers are trusted"),
+	tlsCert:          fs.String("tls-cert", "", "serve https using this PEM certificate file, reloaded when it changes"),
+	tlsKey:           fs.String("tls-key", "", "the PEM private key file for the certificate provided by -tls-cert"),
+	selfSigned:       fs.Bool("self-signed", false, "serve https using an ephemeral self-signed certificate, for development only"),
+	httpRedirect:     fs.String("http-redirect", "", "a <host:port> string where a plain http listener redirects requests to https"),
+	hstsMaxAge:       fs.Duration("hsts-max-age", 0, "send a Strict-Transport-Security header with this max age over https, 0 disables it"),
+	hstsSubdomains:   fs.Bool("hsts-include-subdomains", false, "add includeSubDomains to the Strict-Transport-Security header"),
+	hstsPreload:      fs.Bool("hsts-preload", false, "add preload to the Strict-Transport-Security header"),
+	rateLimits:       fs.String("rate-limit", "", "a comma separated list of <prefix>=<rate>[:<burst>] rules limiting each client to rate requests per second for paths beginning with prefix"),
+	readTimeout:      fs.Duration("read-timeout", 30*time.Second, "the maximum duration for reading an entire request, 0 disables it"),
+	writeTimeout:     fs.Duration("write-timeout", 60*time.Second, "the maximum duration for writing a response, 0 disables it"),
+	idleTimeout:      fs.Duration("idle-timeout", 120*time.Second, "how long idle keep-alive connections are kept open, 0 disables it"),
+	maxHeaderBytes:   fs.Int("max-header-bytes", http.DefaultMaxHeaderBytes, "the maximum size of request headers in bytes"),
+	theme:            fs.Bool("theme", false, "render index, post, archive and tag pages on the server using the embedded templates"),
+	contentDir:       fs.String("content-dir", "", "serve posts, the web root and config from this goblog source tree instead of the embedded content, reloading them as they change"),
+	contentPoll:      fs.Duration("content-poll", time.Second, "how often -content-dir is checked for changes"),
+	liveReload:       fs.Bool("live-reload", false, "reload browsers viewing the blog when -content-dir or its drafts change, for previewing only"),
 }
 
 // Serve will launch an http server and begin serving blog posts
@@ -27,38 +74,173 @@ func Serve() {
 	// 0: goblog, 1: server
 	fs.Parse(os.Args[2:])
 
-	inter := make(chan os.Signal)
+	inter := make(chan os.Signal, 1)
 	signal.Notify(inter, os.Interrupt)
 
-	var mux http.ServeMux
-	// mux.Handle("/assets/", goblog.AssetHandler())
-	mux.Handle("/posts/", goblog.PostsHandler())
-	mux.Handle("/summaries", goblog.SummaryHandler())
-	mux.Handle("/", goblog.WebHandler(goblog.Conf.AppPaths))
+	var ready goblog.Readiness
+	ready.NotReady("starting")
+
+	var m *serveMetrics
+	if *flags.metrics {
+		m = newServeMetrics()
+	}
+
+	if *flags.contentDir != "" {
+		if err := goblog.LoadContent(os.DirFS(*flags.contentDir)); err != nil {
+			log.Fatalf("failed loading content from %v: %v", *flags.contentDir, err)
+		}
+		log.Printf("Serving content from %v\n", *flags.contentDir)
+	}
+
+	var reload *goblog.LiveReload
+	writeTimeout := *flags.writeTimeout
+	if *flags.liveReload {
+		if *flags.contentDir == "" {
+			log.Fatalf("-live-reload requires -content-dir")
+		}
+		reload = goblog.NewLiveReload()
+		// the write timeout would end event streams, leave it
+		// off unless asked for.
+		if !flagSet("write-timeout") {
+			writeTimeout = 0
+		}
+	}
+
+	proxies, err := parseTrustedProxies(*flags.trustedProxies)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	rules, err := parseRateLimits(*flags.rateLimits)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	limiter := newRateLimiter(rules)
+
+	var accessOut io.Writer = os.Stdout
+	if *flags.accessLogFile != "" {
+		rf, err := openRotatingFile(*flags.accessLogFile, *flags.accessLogMaxSize*1024*1024, *flags.accessLogBackups)
+		if err != nil {
+			log.Fatalf("failed to open access log file: %v", err)
+		}
+		accessOut = rf
+	}
+
+	build := func() (http.Handler, error) {
+		return newHandler(m, &ready, proxies, limiter, accessOut, reload)
+	}
+	handler, err := build()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	// in content mode the handler is rebuilt whenever the
+	// content, and so possibly the config, changes.
+	var live swapHandler
+	live.store(handler)
+
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		log.Fatalf("failed configuring tls: %v", err)
+	}
 
 	server := &http.Server{
-		Addr:    *flags.listenAddr,
-		Handler: cors.Default().Handler(&mux),
+		Addr:           *flags.listenAddr,
+		Handler:        &live,
+		TLSConfig:      tlsConfig,
+		ReadTimeout:    *flags.readTimeout,
+		WriteTimeout:   writeTimeout,
+		IdleTimeout:    *flags.idleTimeout,
+		MaxHeaderBytes: *flags.maxHeaderBytes,
 	}
+	if reload != nil {
+		server.RegisterOnShutdown(reload.Close)
+	}
+	servers := []*http.Server{server}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	var httpErr error
-	go func() {
-		log.Printf("Launching goblog @ %v\n", *flags.listenAddr)
-		err := server.ListenAndServe()
+	var redirect *http.Server
+	if *flags.httpRedirect != "" {
+		if tlsConfig == nil {
+			log.Fatalf("-http-redirect requires -tls-cert and -tls-key or -self-signed")
+		}
+		h, err := redirectToHTTPS(*flags.listenAddr)
 		if err != nil {
-			httpErr = err
-			cancel()
+			log.Fatalf("%v", err)
 		}
+		redirect = &http.Server{
+			Addr:           *flags.httpRedirect,
+			Handler:        h,
+			ReadTimeout:    *flags.readTimeout,
+			WriteTimeout:   *flags.writeTimeout,
+			IdleTimeout:    *flags.idleTimeout,
+			MaxHeaderBytes: *flags.maxHeaderBytes,
+		}
+		servers = append(servers, redirect)
+	}
+
+	if err := goblog.Validate(); err != nil {
+		log.Fatalf("failed validating embedded content: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", *flags.listenAddr)
+	if err != nil {
+		log.Fatalf("failed to listen on %v: %v", *flags.listenAddr, err)
+	}
+
+	errs := make(chan error, len(servers))
+	go func() {
+		if tlsConfig != nil {
+			log.Printf("Launching goblog @ https://%v\n", *flags.listenAddr)
+			// certificates are provided by tlsConfig.
+			errs <- server.ServeTLS(ln, "", "")
+			return
+		}
+		log.Printf("Launching goblog @ %v\n", *flags.listenAddr)
+		errs <- server.Serve(ln)
 	}()
+	if redirect != nil {
+		go func() {
+			log.Printf("Redirecting http requests @ %v to https\n", *flags.httpRedirect)
+			errs <- redirect.ListenAndServe()
+		}()
+	}
+	bgctx, stopBackground := context.WithCancel(context.Background())
+	go goblog.WatchSchedule(bgctx)
+	if *flags.contentDir != "" {
+		go watchContent(bgctx, *flags.contentDir, contentDirs, *flags.contentPoll, func() {
+			if err := goblog.LoadContent(os.DirFS(*flags.contentDir)); err != nil {
+				log.Printf("Failed reloading content, continuing to serve the previous content: %v\n", err)
+				return
+			}
+			h, err := build()
+			if err != nil {
+				log.Printf("Failed rebuilding handlers after reloading content: %v\n", err)
+				return
+			}
+			live.store(h)
+			log.Printf("Reloaded content from %v\n", *flags.contentDir)
+			if reload != nil {
+				reload.Notify()
+			}
+		})
+	}
+	if reload != nil {
+		// drafts aren't served, but are likely what is being
+		// previewed.
+		go watchContent(bgctx, *flags.contentDir, []string{"drafts"}, *flags.contentPoll, reload.Notify)
+	}
+	ready.Ready()
 
 	select {
 	case <-inter:
 		log.Printf("Received interupt. Gracefully shutting down server.\n")
+		ready.NotReady("shutting down")
+		stopBackground()
 		tctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		server.Shutdown(tctx)
-	case <-ctx.Done():
+		for _, s := range servers {
+			s.Shutdown(tctx)
+		}
+	case httpErr := <-errs:
 		if httpErr != http.ErrServerClosed {
 			log.Printf("Received http error: %v\n", httpErr)
 			os.Exit(1)
@@ -66,3 +248,103 @@ func Serve() {
 	}
 	os.Exit(0)
 }
+
+// newHandler builds the handler serving the blog, wrapped
+// in the middleware configured by flags and goblog.Conf.
+//
+// When reload is not nil browsers viewing the blog are
+// reloaded whenever it is notified.
+func newHandler(m *serveMetrics, ready *goblog.Readiness, proxies trustedProxies, limiter *rateLimiter, accessOut io.Writer, reload *goblog.LiveReload) (http.Handler, error) {
+	var mux http.ServeMux
+	// mux.Handle("/assets/", goblog.AssetHandler())
+	mux.Handle("/posts/", m.instrument("posts", goblog.PostsHandler()))
+	mux.Handle("/api/posts/", m.instrument("api_posts", goblog.PostAPIHandler()))
+	mux.Handle("/summaries", m.instrument("summaries", goblog.SummaryHandler()))
+	mux.Handle("/tags", m.instrument("tags", goblog.TagsHandler()))
+	mux.Handle("/search", m.instrument("search", goblog.SearchHandler()))
+	mux.Handle("/feed.xml", m.instrument("rss", goblog.RSSHandler(goblog.Conf)))
+	mux.Handle("/atom.xml", m.instrument("atom", goblog.AtomHandler(goblog.Conf)))
+	mux.Handle("/feed.json", m.instrument("json_feed", goblog.JSONFeedHandler(goblog.Conf)))
+	mux.Handle("/sitemap.xml", m.instrument("sitemap", goblog.SitemapHandler(goblog.Conf)))
+	mux.Handle("/robots.txt", m.instrument("robots", goblog.RobotsHandler(goblog.Conf)))
+	if *flags.theme {
+		theme, err := goblog.NewTheme(goblog.TemplatesFS, goblog.Conf)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle("/", m.instrument("theme", theme.Handler(goblog.WebHandler(goblog.Conf, reload))))
+	} else {
+		mux.Handle("/", m.instrument("web", goblog.WebHandler(goblog.Conf, reload)))
+	}
+	if m != nil {
+		mux.Handle("/metrics", m.handler())
+	}
+	mux.Handle("/healthz", goblog.HealthHandler())
+	mux.Handle("/readyz", goblog.ReadyHandler(ready))
+	mux.Handle("/version", goblog.VersionHandler(goblog.Conf))
+
+	c, err := newCORS(goblog.Conf.CORS)
+	if err != nil {
+		return nil, err
+	}
+
+	redirects, err := goblog.NewRedirects(goblog.Conf.Redirects, goblog.DSCache)
+	if err != nil {
+		return nil, err
+	}
+
+	h := limiter.rateLimit(proxies,
+		hsts(*flags.hstsMaxAge, *flags.hstsSubdomains, *flags.hstsPreload,
+			goblog.SecurityHeadersHandler(goblog.Conf.SecurityHeaders,
+				c.Handler(redirects.Handler(goblog.CompressHandler(&mux))),
+			),
+		),
+	)
+	if *flags.contentDir != "" {
+		h = goblog.ContentHandler(h)
+	}
+	if reload != nil {
+		// event streams don't serve content, there's no need
+		// to pin a snapshot for as long as a browser is
+		// connected.
+		h = liveReloadHandler(reload, h)
+	}
+	handler, err := accessLog(*flags.accessLog, accessOut, proxies, h)
+	if err != nil {
+		return nil, err
+	}
+	return goblog.RequestIDHandler(handler), nil
+}
+
+// newTLSConfig builds the tls configuration requested by
+// flags, returning nil when serving plain http.
+func newTLSConfig() (*tls.Config, error) {
+	switch {
+	case *flags.selfSigned:
+		if *flags.tlsCert != "" || *flags.tlsKey != "" {
+			return nil, fmt.Errorf("-self-signed cannot be used with -tls-cert or -tls-key")
+		}
+		cert, err := selfSignedCert(*flags.listenAddr)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using an ephemeral self-signed certificate, do not use this in production.\n")
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{*cert},
+		}, nil
+	case *flags.tlsCert != "" || *flags.tlsKey != "":
+		if *flags.tlsCert == "" || *flags.tlsKey == "" {
+			return nil, fmt.Errorf("both -tls-cert and -tls-key must be provided")
+		}
+		cr, err := newCertReloader(*flags.tlsCert, *flags.tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: cr.GetCertificate,
+		}, nil
+	}
+	return nil, nil
+}
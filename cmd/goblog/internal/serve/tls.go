@@ -0,0 +1,182 @@
+package serve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the certificate and key
+// files are checked for changes.
+const certReloadInterval = 10 * time.Second
+
+// certReloader serves a certificate pair loaded from disk,
+// reloading it whenever either file changes.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.reload(); err != nil {
+		return nil, err
+	}
+	go cr.watch()
+	return cr, nil
+}
+
+// latestModTime returns the most recent modification time
+// of the certificate and key files.
+func (cr *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{cr.certFile, cr.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload loads the certificate pair if it changed since it
+// was last loaded, reporting whether it did.
+func (cr *certReloader) reload() (bool, error) {
+	modTime, err := cr.latestModTime()
+	if err != nil {
+		return false, err
+	}
+	cr.mu.RLock()
+	unchanged := modTime.Equal(cr.modTime)
+	cr.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return false, err
+	}
+	cr.mu.Lock()
+	cr.cert, cr.modTime = &cert, modTime
+	cr.mu.Unlock()
+	return true, nil
+}
+
+func (cr *certReloader) watch() {
+	for range time.Tick(certReloadInterval) {
+		reloaded, err := cr.reload()
+		switch {
+		case err != nil:
+			// a pair mid-rotation may not match yet, keep
+			// serving the last good certificate.
+			log.Printf("Failed reloading TLS certificate, keeping the current one: %v\n", err)
+		case reloaded:
+			log.Printf("Reloaded TLS certificate from %v\n", cr.certFile)
+		}
+	}
+}
+
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// selfSignedCert generates an ephemeral, in memory, self
+// signed certificate for the host of addr.
+//
+// It is only suitable for development.
+func selfSignedCert(addr string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"GoBlog Development"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(7 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if host != "localhost" {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// hsts wraps h, setting a Strict-Transport-Security header
+// on responses to requests made over TLS.
+//
+// A zero maxAge disables the header.
+func hsts(maxAge time.Duration, includeSubdomains, preload bool, h http.Handler) http.Handler {
+	if maxAge <= 0 {
+		return h
+	}
+	value := "max-age=" + strconv.FormatInt(int64(maxAge.Seconds()), 10)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if preload {
+		value += "; preload"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectToHTTPS redirects every request to the same URL
+// over https, served by the listener at tlsAddr.
+func redirectToHTTPS(tlsAddr string) (http.Handler, error) {
+	_, port, err := net.SplitHostPort(tlsAddr)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse tls listen address: %w", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}), nil
+}
@@ -2,9 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 
 	"github.com/fatih/color"
 	"github.com/ldelossa/goblog"
@@ -27,19 +27,30 @@ goblog serve   - serve your blog posts, assests, and web root over http
 goblog posts   - list, view, and remove published posts
 goblog drafts  - list, create, publish, and delete draft blog posts
 goblog publish - build a new goblog binary with the latest posts and web root
-goblog preview - preview your blog by running the code in $HOME/src directly
+                 (--compress to pre-compress web and posts assets first)
+goblog preview - preview your blog by serving the posts, web root and config in $HOME/src
+                 directly, reloading them and your browser as they change
+                 (accepts the flags of 'serve')
 `
 
+var publishFS = flag.NewFlagSet("publish", flag.ExitOnError)
+
+var publishFlags = struct {
+	compress *bool
+}{
+	compress: publishFS.Bool("compress", false, "write gzip and brotli compressed siblings of web and posts assets before building"),
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Printf("Error: subcommand required\n\n")
-		fmt.Println(usage)
+		fmt.Print(usage)
 		os.Exit(1)
 	}
 
 	switch os.Args[1] {
 	case "--help":
-		fmt.Println(usage)
+		fmt.Print(usage)
 		os.Exit(0)
 	case "init":
 		initialize.Initialize(context.TODO())
@@ -53,24 +64,28 @@ func main() {
 	case "drafts":
 		drafts.Root(context.TODO())
 	case "publish":
+		// 0: goblog, 1: publish
+		publishFS.Parse(os.Args[2:])
+		if *publishFlags.compress {
+			_, err := initialize.NewCompressDecision().Exec(context.TODO())
+			if err != nil {
+				color.Red("Error: %v", err)
+				os.Exit(1)
+			}
+		}
 		_, err := initialize.NewBuildDecision().Exec(context.TODO())
 		if err != nil {
 			initialize.Initialize(context.TODO())
 		}
 	case "preview":
-		cmd := exec.Command("go", "run", "./cmd/goblog/", "serve")
-		cmd.Dir = goblog.Src
-		cmd.Stdout = os.Stdout
-		cmd.Stdin = os.Stdin
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		if err != nil {
-			color.Red("Error: failed to start goblog from src directory: %v", err)
-			os.Exit(1)
-		}
+		// serve the source tree from disk, reloading browsers
+		// as it changes and passing any remaining flags through
+		// to serve.
+		os.Args = append([]string{os.Args[0], "serve", "-content-dir", goblog.Src, "-live-reload"}, os.Args[2:]...)
+		serve.Serve()
 		os.Exit(0)
 	default:
 		fmt.Printf("Error: unrecognized subcommand: %s\n", os.Args[1])
-		fmt.Println(usage)
+		fmt.Print(usage)
 	}
 }
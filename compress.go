@@ -0,0 +1,442 @@
+package goblog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Encodings lists the content encodings GoBlog can serve
+// along with the file extension of pre-compressed siblings,
+// in order of preference.
+var Encodings = []struct {
+	Name string
+	Ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// compressMinSize is the smallest body worth compressing.
+const compressMinSize = 1024
+
+// Compress compresses b with the named content encoding at
+// the best compression level, it is meant for compressing
+// assets ahead of time.
+func Compress(encoding string, b []byte) ([]byte, error) {
+	return compress(encoding, b, brotli.DefaultCompression, gzip.BestCompression)
+}
+
+// compress compresses b with the named content encoding at
+// the provided brotli or gzip level.
+func compress(encoding string, b []byte, brLevel, gzipLevel int) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "br":
+		w = brotli.NewWriterLevel(&buf, brLevel)
+	case "gzip":
+		gw, err := gzip.NewWriterLevel(&buf, gzipLevel)
+		if err != nil {
+			return nil, err
+		}
+		w = gw
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %v", encoding)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reverses Compress.
+func decompress(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "br":
+		return brotli.NewReader(r), nil
+	case "gzip":
+		return gzip.NewReader(r)
+	}
+	return nil, fmt.Errorf("unsupported encoding: %v", encoding)
+}
+
+// Compressible reports whether a response of the given
+// Content-Type benefits from compression.
+func Compressible(contentType string) bool {
+	ct, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case ct == "text/event-stream":
+		return false
+	case strings.HasPrefix(ct, "text/"):
+		return true
+	case strings.HasSuffix(ct, "+json"), strings.HasSuffix(ct, "+xml"):
+		return true
+	}
+	switch ct {
+	case "application/json",
+		"application/javascript",
+		"application/xml",
+		"application/wasm",
+		"image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// acceptedEncodings returns the encodings from Encodings
+// the client accepts, in order of preference.
+func acceptedEncodings(r *http.Request) []string {
+	header := r.Header.Get("Accept-Encoding")
+	if header == "" {
+		return nil
+	}
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			name = part[:i]
+			param := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[strings.TrimSpace(name)] = q > 0
+	}
+	var encs []string
+	for _, enc := range Encodings {
+		ok, found := accepted[enc.Name]
+		if !found {
+			ok = accepted["*"]
+		}
+		if ok {
+			encs = append(encs, enc.Name)
+		}
+	}
+	return encs
+}
+
+// Precompressed records which files in an fs.FS have valid
+// pre-compressed siblings, keyed by the original file's path
+// and then by encoding.
+type Precompressed map[string]map[string]string
+
+// NewPrecompressed finds the pre-compressed siblings (such as
+// "app.js.gz" and "app.js.br") of the files tagged in etags.
+//
+// A sibling is only used if it decompresses to the same contents
+// as the original file, so stale siblings left behind after
+// editing a file are ignored.
+func NewPrecompressed(fsys fs.FS, etags ETags) (Precompressed, error) {
+	pc := Precompressed{}
+	for p, etag := range etags {
+		for _, enc := range Encodings {
+			sibling := p + enc.Ext
+			if _, ok := etags[sibling]; !ok {
+				continue
+			}
+			ok, err := siblingMatches(fsys, sibling, enc.Name, etag)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if pc[p] == nil {
+				pc[p] = map[string]string{}
+			}
+			pc[p][enc.Name] = sibling
+		}
+	}
+	return pc, nil
+}
+
+func siblingMatches(fsys fs.FS, sibling, encoding, etag string) (bool, error) {
+	f, err := fsys.Open(sibling)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	r, err := decompress(encoding, f)
+	if err != nil {
+		// not a valid compressed file, just ignore it.
+		return false, nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, nil
+	}
+	return `"`+hex.EncodeToString(h.Sum(nil)[:16])+`"` == etag, nil
+}
+
+// servePrecompressed serves a pre-compressed sibling of the
+// file at path p, if one exists which the client accepts.
+//
+// It reports whether a response was written.
+func servePrecompressed(w http.ResponseWriter, r *http.Request, fsys fs.FS, p string, etags ETags, pc Precompressed) bool {
+	siblings, ok := pc[p]
+	if !ok {
+		return false
+	}
+	addVary(w.Header(), "Accept-Encoding")
+	for _, enc := range acceptedEncodings(r) {
+		sibling, ok := siblings[enc]
+		if !ok {
+			continue
+		}
+		f, fi, err := openAsset(fsys, sibling)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		ct := mime.TypeByExtension(path.Ext(p))
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", ct)
+		w.Header().Set("Content-Encoding", enc)
+		serveAsset(w, r, f, fi, variantETag(etags[p], enc))
+		return true
+	}
+	return false
+}
+
+// compressCache holds compressed response bodies keyed by
+// encoding and a hash of the uncompressed body, so identical
+// responses are only compressed once.
+var compressCache = struct {
+	sync.Mutex
+	bodies map[string][]byte
+	// size is the total length of bodies.
+	size int
+}{bodies: map[string][]byte{}}
+
+// compressCacheMaxBytes bounds the total size of cached
+// bodies.
+const compressCacheMaxBytes = 32 << 20
+
+func compressCached(encoding string, b []byte) ([]byte, error) {
+	sum := sha256.Sum256(b)
+	key := encoding + ":" + hex.EncodeToString(sum[:])
+
+	compressCache.Lock()
+	cached, ok := compressCache.bodies[key]
+	compressCache.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	// responses are compressed on the request path, favor
+	// speed over the best ratio.
+	compressed, err := compress(encoding, b, brotli.DefaultCompression, gzip.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) > compressCacheMaxBytes/8 {
+		// a few large bodies would crowd out everything else.
+		return compressed, nil
+	}
+
+	compressCache.Lock()
+	defer compressCache.Unlock()
+	if _, ok := compressCache.bodies[key]; ok {
+		return compressed, nil
+	}
+	// evict arbitrary entries until the body fits, map
+	// iteration order is random enough for our purposes.
+	for k, v := range compressCache.bodies {
+		if compressCache.size+len(compressed) <= compressCacheMaxBytes {
+			break
+		}
+		delete(compressCache.bodies, k)
+		compressCache.size -= len(v)
+	}
+	compressCache.bodies[key] = compressed
+	compressCache.size += len(compressed)
+	return compressed, nil
+}
+
+// CompressHandler wraps h, compressing compressible responses
+// with the client's preferred encoding.
+//
+// Responses which already carry a Content-Encoding, such as
+// pre-compressed assets, and requests for byte ranges are
+// passed through untouched.
+func CompressHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encs := acceptedEncodings(r)
+		if len(encs) == 0 || r.Header.Get("Range") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encs[0],
+			ifNoneMatch:    r.Header.Get("If-None-Match"),
+		}
+		h.ServeHTTP(cw, r)
+		cw.finish()
+	})
+}
+
+// compressWriter buffers a compressible response so it can
+// be compressed as a whole once the handler returns.
+//
+// Once a response is determined to be incompressible the
+// writer passes everything straight through.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	ifNoneMatch string
+	status      int
+	wroteHeader bool
+	passthrough bool
+	discard     bool
+	buf         bytes.Buffer
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+
+	hdr := cw.Header()
+	addVary(hdr, "Accept-Encoding")
+	if status != http.StatusOK ||
+		hdr.Get("Content-Encoding") != "" ||
+		!Compressible(hdr.Get("Content-Type")) {
+		cw.passthrough = true
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	// handlers only know the ETag of the uncompressed
+	// representation, so clients revalidating a compressed
+	// variant are answered here.
+	etag := variantETag(hdr.Get("ETag"), cw.encoding)
+	if etag != "" && etagMatches(cw.ifNoneMatch, etag) {
+		cw.passthrough, cw.discard = true, true
+		hdr.Set("ETag", etag)
+		hdr.Del("Content-Type")
+		hdr.Del("Content-Length")
+		cw.ResponseWriter.WriteHeader(http.StatusNotModified)
+	}
+}
+
+// etagMatches reports whether etag is listed in the
+// provided If-None-Match header value.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary adds v to the Vary header unless already present.
+func addVary(hdr http.Header, v string) {
+	for _, existing := range hdr.Values("Vary") {
+		for _, f := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), v) {
+				return
+			}
+		}
+	}
+	hdr.Add("Vary", v)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case cw.discard:
+		return len(b), nil
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.buf.Write(b)
+}
+
+// Flush implements http.Flusher. Flushing a buffered response
+// gives up on compressing it.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.passthrough {
+		cw.passthrough = true
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+func (cw *compressWriter) finish() {
+	if !cw.wroteHeader || cw.passthrough {
+		return
+	}
+	body := cw.buf.Bytes()
+	hdr := cw.Header()
+	if len(body) < compressMinSize {
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.ResponseWriter.Write(body)
+		return
+	}
+	compressed, err := compressCached(cw.encoding, body)
+	if err != nil {
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.ResponseWriter.Write(body)
+		return
+	}
+	hdr.Set("Content-Encoding", cw.encoding)
+	hdr.Set("Content-Length", strconv.Itoa(len(compressed)))
+	hdr.Del("Accept-Ranges")
+	if etag := hdr.Get("ETag"); etag != "" {
+		hdr.Set("ETag", variantETag(etag, cw.encoding))
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	cw.ResponseWriter.Write(compressed)
+}
@@ -1,5 +1,11 @@
 package goblog
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
 // Version is the goblog binary version.
 //
 // This will increment when a new GoBlog is released.
@@ -25,5 +31,173 @@ type Config struct {
 	// your web application's index.html
 	//
 	// This is how deep linking is supported.
+	//
+	// Each entry is a route pattern, see Route, such as
+	// "/about", "/post/:slug" or "/archive/*". Patterns
+	// without a wildcard match exactly. Prefixing a pattern
+	// with "!" excludes the paths it matches, the most
+	// specific matching pattern decides.
+	//
+	// Extension-less paths which exist in neither AppPaths
+	// nor the web root, and are not excluded, also serve
+	// index.html.
 	AppPaths []string
+	// PostRoutes are route patterns, within AppPaths, which
+	// address a single post by its slug such as "/post/:slug".
+	//
+	// When index.html is served for a post route its title,
+	// Open Graph and Twitter card meta tags describe the post
+	// so shared links render a preview.
+	//
+	// If empty "/post/:slug" is assumed.
+	PostRoutes []string `json:"post_routes" yaml:"post_routes"`
+	// BaseURL is the absolute URL your blog is served from,
+	// such as "https://blog.example.com".
+	//
+	// It is used to build absolute links in syndication feeds.
+	// If empty, links are derived from the incoming request.
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Title is the name of your blog, used as the title
+	// of syndication feeds.
+	Title string `json:"title" yaml:"title"`
+	// Description is a short description of your blog,
+	// used as the description of syndication feeds.
+	Description string `json:"description" yaml:"description"`
+	// Robots configures the robots.txt served alongside
+	// your blog.
+	Robots Robots `json:"robots" yaml:"robots"`
+	// CORS configures the cross-origin resource sharing
+	// policy applied to everything GoBlog serves.
+	//
+	// If left empty any origin may make simple requests.
+	CORS CORS `json:"cors" yaml:"cors"`
+	// SecurityHeaders configures the security related
+	// headers set on every response.
+	SecurityHeaders SecurityHeaders `json:"security_headers" yaml:"security_headers"`
+	// Redirects send requests for old URLs elsewhere, such
+	// as after a post or page is renamed.
+	Redirects []Redirect `json:"redirects" yaml:"redirects"`
+}
+
+// Validate checks the configuration for values GoBlog
+// cannot serve with.
+func (c Config) Validate() error {
+	if err := c.CORS.Validate(); err != nil {
+		return fmt.Errorf("invalid cors config: %w", err)
+	}
+	if _, err := ParseAppRoutes(c.AppPaths); err != nil {
+		return err
+	}
+	if _, err := c.postRoutes(); err != nil {
+		return err
+	}
+	if _, err := NewRedirects(c.Redirects, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CORS is a cross-origin resource sharing policy.
+type CORS struct {
+	// AllowedOrigins lists the origins which may make
+	// cross-origin requests. An origin may contain a single
+	// wildcard to match subdomains, such as
+	// "https://*.example.com", and "*" allows every origin.
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+	// AllowedMethods lists the methods cross-origin requests
+	// may use. If empty GET, POST and HEAD are allowed.
+	AllowedMethods []string `json:"allowed_methods" yaml:"allowed_methods"`
+	// AllowedHeaders lists the non-simple headers cross-origin
+	// requests may carry. "*" allows any header.
+	AllowedHeaders []string `json:"allowed_headers" yaml:"allowed_headers"`
+	// AllowCredentials allows cross-origin requests to
+	// include cookies and other credentials.
+	AllowCredentials bool `json:"allow_credentials" yaml:"allow_credentials"`
+	// MaxAge is how long, in seconds, browsers may cache
+	// the result of a preflight request.
+	MaxAge int `json:"max_age" yaml:"max_age"`
+}
+
+// IsZero reports whether no CORS policy was configured.
+func (c CORS) IsZero() bool {
+	return len(c.AllowedOrigins) == 0 &&
+		len(c.AllowedMethods) == 0 &&
+		len(c.AllowedHeaders) == 0 &&
+		!c.AllowCredentials &&
+		c.MaxAge == 0
+}
+
+// Validate checks the policy for malformed entries.
+func (c CORS) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return fmt.Errorf("the \"*\" origin cannot be combined with allowing credentials")
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("origin %q may only contain a single wildcard", origin)
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("origin %q must be of the form scheme://host[:port]", origin)
+		}
+		if u.Path != "" || u.RawQuery != "" {
+			return fmt.Errorf("origin %q must not contain a path or query", origin)
+		}
+	}
+	for _, method := range c.AllowedMethods {
+		if method == "" || strings.ToUpper(method) != method {
+			return fmt.Errorf("method %q must be an upper case http method", method)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("max age must not be negative")
+	}
+	return nil
+}
+
+// Robots configures the rules written to robots.txt.
+//
+// A "Sitemap" directive pointing at the blog's sitemap.xml
+// is always included.
+type Robots struct {
+	// Allow lists path prefixes crawlers may index.
+	Allow []string `json:"allow" yaml:"allow"`
+	// Disallow lists path prefixes crawlers should not index.
+	Disallow []string `json:"disallow" yaml:"disallow"`
+}
+
+// SecurityHeaders configures the security related headers
+// GoBlog sets on responses.
+//
+// Empty fields use GoBlog's defaults and a value of "-"
+// omits the header entirely.
+//
+// The string "{nonce}" in the Content-Security-Policy is
+// replaced with a random nonce generated per request. The
+// same nonce is added to the script and style elements of
+// the served index.html.
+type SecurityHeaders struct {
+	ContentSecurityPolicy string `json:"content_security_policy" yaml:"content_security_policy"`
+	ContentTypeOptions    string `json:"content_type_options" yaml:"content_type_options"`
+	ReferrerPolicy        string `json:"referrer_policy" yaml:"referrer_policy"`
+	PermissionsPolicy     string `json:"permissions_policy" yaml:"permissions_policy"`
+	FrameOptions          string `json:"frame_options" yaml:"frame_options"`
+	// Overrides replace the headers above for requests
+	// whose path begins with PathPrefix. When several
+	// overrides match the longest prefix wins.
+	Overrides []SecurityHeadersOverride `json:"overrides" yaml:"overrides"`
+}
+
+// SecurityHeadersOverride replaces the non-empty headers
+// for requests under PathPrefix.
+type SecurityHeadersOverride struct {
+	PathPrefix            string `json:"path_prefix" yaml:"path_prefix"`
+	ContentSecurityPolicy string `json:"content_security_policy" yaml:"content_security_policy"`
+	ContentTypeOptions    string `json:"content_type_options" yaml:"content_type_options"`
+	ReferrerPolicy        string `json:"referrer_policy" yaml:"referrer_policy"`
+	PermissionsPolicy     string `json:"permissions_policy" yaml:"permissions_policy"`
+	FrameOptions          string `json:"frame_options" yaml:"frame_options"`
 }
@@ -2,6 +2,8 @@ package goblog
 
 import (
 	"embed"
+	"fmt"
+	"io/fs"
 
 	"gopkg.in/yaml.v3"
 )
@@ -9,18 +11,35 @@ import (
 var Conf Config
 
 func init() {
+	conf, err := DecodeConfig(ConfigFS)
+	if err != nil {
+		panic(err.Error())
+	}
+	Conf = conf
+}
+
+// DecodeConfig decodes the "config/config.yaml" found in
+// fsys, such as an os.DirFS of GoBlog's source.
+func DecodeConfig(fsys fs.FS) (Config, error) {
 	var conf Config
-	f, err := ConfigFS.Open("config/config.yaml")
+	f, err := fsys.Open("config/config.yaml")
 	if err != nil {
-		panic("could not open config: " + err.Error())
+		return conf, fmt.Errorf("could not open config: %w", err)
 	}
+	defer f.Close()
 
 	err = yaml.NewDecoder(f).Decode(&conf)
 	if err != nil {
-		panic("could not json decode config: " + err.Error())
+		return conf, fmt.Errorf("could not decode config: %w", err)
 	}
-	Conf = conf
+	return conf, nil
 }
 
 //go:embed config
-var ConfigFS embed.FS
+var embeddedConfig embed.FS
+
+// ConfigFS holds GoBlog's config, rooted at "config/".
+//
+// It is the config embedded when GoBlog was built unless
+// replaced by LoadContent.
+var ConfigFS fs.FS = embeddedConfig
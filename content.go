@@ -0,0 +1,182 @@
+package goblog
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// content is a snapshot of the content GoBlog serves and the
+// caches derived from it.
+//
+// A snapshot is never modified once loaded, LoadContent
+// replaces it as a whole. Only which of its posts are
+// published and which have been rendered change over time.
+type content struct {
+	postsFS     fs.FS
+	webFS       fs.FS
+	templatesFS fs.FS
+	posts       DateSortable
+	index       *SearchIndex
+
+	webETags           ETags
+	postsETags         ETags
+	webPrecompressed   Precompressed
+	postsPrecompressed Precompressed
+	// errorTmpl is the error page template, or errorTmplErr
+	// why it could not be parsed.
+	errorTmpl    *template.Template
+	errorTmplErr error
+
+	published publishedPosts
+	rendered  renderedPosts
+}
+
+// current holds the *content being served.
+var current atomic.Value
+
+// embedded ensures the embedded content is only stored in
+// current when LoadContent has not replaced it.
+var embedded sync.Once
+
+// loaded returns the content currently being served.
+func loaded() *content {
+	embedded.Do(func() {
+		c := &content{
+			postsFS:            PostsFS,
+			webFS:              WebFS,
+			templatesFS:        TemplatesFS,
+			posts:              DSCache,
+			index:              Index,
+			webETags:           webETags,
+			postsETags:         postsETags,
+			webPrecompressed:   webPrecompressed,
+			postsPrecompressed: postsPrecompressed,
+		}
+		c.errorTmpl, c.errorTmplErr = parseErrorTemplate(WebFS, TemplatesFS)
+		c.refreshPublished(time.Now())
+		current.Store(c)
+	})
+	return current.Load().(*content)
+}
+
+// contentKey is the context key of the content a request
+// is served from.
+type contentKey struct{}
+
+// contentOf returns the content r is served from, which is
+// the content currently being served unless ContentHandler
+// pinned the content r began with.
+func contentOf(r *http.Request) *content {
+	if c, ok := r.Context().Value(contentKey{}).(*content); ok {
+		return c
+	}
+	return loaded()
+}
+
+// ContentHandler wraps h, serving each request entirely from
+// the content loaded when it began, even if LoadContent
+// replaces it midway.
+//
+// It is only required when content is loaded at runtime.
+func ContentHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contentKey{}, loaded())
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// LoadContent replaces the embedded content with the GoBlog
+// source tree found in fsys, such as an os.DirFS of Src.
+//
+// fsys must hold the "posts", "web" and "config" directories.
+// A "templates" directory is optional, the embedded theme is
+// used when it is missing.
+//
+// Content is only replaced once all of it loads, on error the
+// current content remains in place.
+//
+// Requests are served from a snapshot of the content. The
+// exported variables such as Conf and DSCache are updated
+// too, for building handlers around the new content, and
+// once serving begins only the goroutine calling LoadContent
+// may read them.
+func LoadContent(fsys fs.FS) error {
+	for _, dir := range []string{"posts", "web", "config"} {
+		fi, err := fs.Stat(fsys, dir)
+		if err != nil {
+			return fmt.Errorf("could not find %v directory: %w", dir, err)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("%v is not a directory", dir)
+		}
+	}
+
+	conf, err := DecodeConfig(fsys)
+	if err != nil {
+		return err
+	}
+	if err := conf.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	posts, err := newDSCache(fsys)
+	if err != nil {
+		return fmt.Errorf("could not create DSCache: %w", err)
+	}
+	idx, err := newSearchIndex(fsys, posts)
+	if err != nil {
+		return fmt.Errorf("could not create search index: %w", err)
+	}
+	wETags, err := NewETags(fsys, "web")
+	if err != nil {
+		return fmt.Errorf("could not compute web etags: %w", err)
+	}
+	pETags, err := NewETags(fsys, "posts")
+	if err != nil {
+		return fmt.Errorf("could not compute posts etags: %w", err)
+	}
+	wPrecompressed, err := NewPrecompressed(fsys, wETags)
+	if err != nil {
+		return fmt.Errorf("could not find pre-compressed web assets: %w", err)
+	}
+	pPrecompressed, err := NewPrecompressed(fsys, pETags)
+	if err != nil {
+		return fmt.Errorf("could not find pre-compressed posts assets: %w", err)
+	}
+	templates := fs.FS(embeddedTemplates)
+	if fi, err := fs.Stat(fsys, "templates"); err == nil && fi.IsDir() {
+		templates = fsys
+	}
+	errorTmpl, err := parseErrorTemplate(fsys, templates)
+	if err != nil {
+		return fmt.Errorf("could not parse error page: %w", err)
+	}
+
+	c := &content{
+		postsFS:            fsys,
+		webFS:              fsys,
+		templatesFS:        templates,
+		posts:              posts,
+		index:              idx,
+		webETags:           wETags,
+		postsETags:         pETags,
+		webPrecompressed:   wPrecompressed,
+		postsPrecompressed: pPrecompressed,
+		errorTmpl:          errorTmpl,
+	}
+	c.refreshPublished(time.Now())
+
+	// the embedded content is never served once replaced.
+	embedded.Do(func() {})
+	current.Store(c)
+
+	PostsFS, WebFS, ConfigFS, TemplatesFS = fsys, fsys, fsys, templates
+	Conf = conf
+	DSCache, Index = posts, idx
+	return nil
+}
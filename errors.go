@@ -0,0 +1,200 @@
+package goblog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// requestIDKey is the context key holding a request's ID.
+type requestIDKey struct{}
+
+// RequestID returns the ID assigned to the request with
+// ctx by RequestIDHandler, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// maxRequestIDLen bounds the length of request IDs accepted
+// from clients.
+const maxRequestIDLen = 128
+
+// validRequestID reports whether an X-Request-ID provided by
+// a client is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_.:", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDHandler wraps h, assigning every request an ID
+// available via RequestID and returned in the X-Request-ID
+// response header.
+//
+// A well formed X-Request-ID sent by the client, typically
+// a proxy, is used as is.
+func RequestIDHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			b := make([]byte, 8)
+			if _, err := rand.Read(b); err != nil {
+				http.Error(w, "failed generating request id: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// errorBody is the JSON representation of an error.
+type errorBody struct {
+	Status    int    `json:"status"`
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorPageData is provided to error page templates.
+type errorPageData struct {
+	Status     int
+	StatusText string
+	Message    string
+	RequestID  string
+	Nonce      string
+}
+
+// Error replies to r with the provided error message and
+// status code.
+//
+// Clients accepting "application/json" receive an errorBody.
+// Everyone else receives an HTML page, the first of the
+// following found in WebFS:
+//
+//	web/<status>.html - a static page, such as web/404.html
+//	web/500.html      - a static page for any 5xx status
+//	web/error.html    - an html/template given the status,
+//	                    message and request ID
+//
+// falling back to the error.html template in TemplatesFS.
+func Error(w http.ResponseWriter, r *http.Request, msg string, status int) {
+	h := w.Header()
+	// headers describing the representation which failed
+	// to be served no longer apply.
+	for _, k := range []string{"Content-Length", "Content-Encoding", "ETag", "Last-Modified", "Cache-Control"} {
+		h.Del(k)
+	}
+	h.Set("X-Content-Type-Options", "nosniff")
+	addVary(h, "Accept")
+
+	if acceptsJSON(r) {
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(errorBody{
+			Status:    status,
+			Error:     msg,
+			RequestID: RequestID(r.Context()),
+		})
+		return
+	}
+
+	page, err := errorPage(r, msg, status)
+	if err != nil {
+		http.Error(w, msg, status)
+		return
+	}
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(page)
+}
+
+// acceptsJSON reports whether the client explicitly accepts
+// JSON responses.
+func acceptsJSON(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, mt := range strings.Split(v, ",") {
+			mt = strings.TrimSpace(strings.SplitN(mt, ";", 2)[0])
+			if strings.EqualFold(mt, "application/json") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errorPage renders the HTML error page for status.
+func errorPage(r *http.Request, msg string, status int) ([]byte, error) {
+	c := contentOf(r)
+	nonce := Nonce(r.Context())
+
+	static := []string{"web/" + strconv.Itoa(status) + ".html"}
+	if status >= 500 {
+		static = append(static, "web/500.html")
+	}
+	for _, p := range static {
+		b, err := fs.ReadFile(c.webFS, p)
+		if err != nil {
+			continue
+		}
+		if nonce != "" {
+			b = injectNonce(b, nonce)
+		}
+		return b, nil
+	}
+
+	tmpl, err := c.errorTemplate()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, errorPageData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    msg,
+		RequestID:  RequestID(r.Context()),
+		Nonce:      nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// errorTemplate returns the error page template of c, parsed
+// when c was loaded.
+func (c *content) errorTemplate() (*template.Template, error) {
+	return c.errorTmpl, c.errorTmplErr
+}
+
+// parseErrorTemplate parses web/error.html if the web root
+// provides one, otherwise the built in error.html template.
+func parseErrorTemplate(webFS, templatesFS fs.FS) (*template.Template, error) {
+	if _, err := fs.Stat(webFS, "web/error.html"); err == nil {
+		tmpl, err := template.ParseFS(webFS, "web/error.html")
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing web/error.html: %w", err)
+		}
+		return tmpl, nil
+	}
+	return template.ParseFS(templatesFS, "templates/error.html")
+}
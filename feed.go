@@ -0,0 +1,345 @@
+package goblog
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rss is an RSS 2.0 document.
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	GUID        rssGUID   `xml:"guid"`
+	PubDate     string    `xml:"pubDate"`
+	Content     *rssCDATA `xml:"content:encoded,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+// atomFeed is an Atom (RFC 4287) document.
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Author   atomAuthor  `xml:"author"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string       `xml:"title"`
+	ID        string       `xml:"id"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Link      atomLink     `xml:"link"`
+	Summary   string       `xml:"summary"`
+	Content   *atomContent `xml:"content,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// jsonFeed is a JSON Feed 1.1 document.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	Image         string `json:"image,omitempty"`
+	DatePublished string `json:"date_published,omitempty"`
+	ContentHTML   string `json:"content_html,omitempty"`
+	ContentText   string `json:"content_text,omitempty"`
+}
+
+// RSSHandler serves an RSS 2.0 feed of the published posts.
+//
+// The "limit" query parameter bounds the number of items
+// and the "full" query parameter, when true, includes each
+// post's rendered markdown as the item's content.
+func RSSHandler(conf Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		posts, full, err := feedParams(r)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		base := baseURL(conf, r)
+
+		doc := rss{
+			Version: "2.0",
+			Content: "http://purl.org/rss/1.0/modules/content/",
+			Channel: rssChannel{
+				Title:       conf.Title,
+				Link:        base + "/",
+				Description: conf.Description,
+			},
+		}
+		if len(posts) > 0 {
+			doc.Channel.LastBuildDate = posts[0].Date.Format(time.RFC1123Z)
+		}
+		for _, post := range posts {
+			link := absURL(base, post.Path)
+			item := rssItem{
+				Title:       post.Title,
+				Link:        link,
+				Description: post.Summary,
+				GUID:        rssGUID{IsPermaLink: true, Value: link},
+				PubDate:     post.Date.Format(time.RFC1123Z),
+			}
+			if full {
+				html, err := contentOf(r).renderPost(post.Path)
+				if err != nil {
+					Error(w, r, "failed rendering post: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				item.Content = &rssCDATA{Value: html}
+			}
+			doc.Channel.Items = append(doc.Channel.Items, item)
+		}
+
+		writeXML(w, r, "application/rss+xml; charset=UTF-8", doc)
+	}
+}
+
+// AtomHandler serves an Atom feed of the published posts.
+//
+// It accepts the same query parameters as RSSHandler.
+func AtomHandler(conf Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		posts, full, err := feedParams(r)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		base := baseURL(conf, r)
+
+		doc := atomFeed{
+			Title:    conf.Title,
+			Subtitle: conf.Description,
+			ID:       base + "/",
+			Updated:  time.Now().UTC().Format(time.RFC3339),
+			Author:   atomAuthor{Name: conf.Title},
+			Links: []atomLink{
+				{Href: base + "/"},
+				{Href: absURL(base, r.URL.Path), Rel: "self", Type: "application/atom+xml"},
+			},
+		}
+		// Atom requires an updated time, feeds without posts
+		// are stamped with the current time.
+		if len(posts) > 0 {
+			doc.Updated = posts[0].Date.Format(time.RFC3339)
+		}
+		for _, post := range posts {
+			link := absURL(base, post.Path)
+			entry := atomEntry{
+				Title:     post.Title,
+				ID:        link,
+				Updated:   post.Date.Format(time.RFC3339),
+				Published: post.Date.Format(time.RFC3339),
+				Link:      atomLink{Href: link},
+				Summary:   post.Summary,
+			}
+			if full {
+				html, err := contentOf(r).renderPost(post.Path)
+				if err != nil {
+					Error(w, r, "failed rendering post: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				entry.Content = &atomContent{Type: "html", Value: html}
+			}
+			doc.Entries = append(doc.Entries, entry)
+		}
+
+		writeXML(w, r, "application/atom+xml; charset=UTF-8", doc)
+	}
+}
+
+// JSONFeedHandler serves a JSON Feed 1.1 document of the posts
+// in DSCache.
+//
+// It accepts the same query parameters as RSSHandler.
+// When full content is requested each item carries the rendered
+// markdown as content_html and the raw markdown as content_text,
+// otherwise content_text holds the post's summary as the spec
+// requires one of the two to be present.
+func JSONFeedHandler(conf Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		posts, full, err := feedParams(r)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		base := baseURL(conf, r)
+		c := contentOf(r)
+
+		doc := jsonFeed{
+			Version:     "https://jsonfeed.org/version/1.1",
+			Title:       conf.Title,
+			HomePageURL: base + "/",
+			FeedURL:     absURL(base, r.URL.Path),
+			Description: conf.Description,
+			Items:       []jsonFeedItem{},
+		}
+		for _, post := range posts {
+			link := absURL(base, post.Path)
+			item := jsonFeedItem{
+				ID:            link,
+				URL:           link,
+				Title:         post.Title,
+				Summary:       post.Summary,
+				DatePublished: post.Date.Format(time.RFC3339),
+				ContentText:   post.Summary,
+			}
+			if post.Hero != "" {
+				item.Image = heroURL(base, post.Hero)
+			}
+			if full {
+				p, err := readPost(c.postsFS, post.Path)
+				if err != nil {
+					Error(w, r, "failed reading post: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				html, err := c.renderMarkdown(p)
+				if err != nil {
+					Error(w, r, "failed rendering post: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				item.ContentHTML = html
+				item.ContentText = p.MarkDown.Value
+			}
+			doc.Items = append(doc.Items, item)
+		}
+
+		w.Header().Set("Content-Type", "application/feed+json; charset=UTF-8")
+		err = json.NewEncoder(w).Encode(doc)
+		if err != nil {
+			Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// feedParams parses the query parameters common to all feeds,
+// returning the posts the feed should contain and whether
+// full post content was requested.
+func feedParams(r *http.Request) (DateSortable, bool, error) {
+	lim, err := parseLimit(r)
+	if err != nil {
+		return nil, false, err
+	}
+	var full bool
+	if tmp := r.URL.Query().Get("full"); tmp != "" {
+		full, err = strconv.ParseBool(tmp)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not parse full param: %w", err)
+		}
+	}
+	return limitPosts(contentOf(r).publishedPosts(), lim), full, nil
+}
+
+// baseURL returns the absolute URL the blog is served from,
+// without a trailing slash.
+//
+// The configured BaseURL is preferred, otherwise one is derived
+// from the incoming request.
+func baseURL(conf Config, r *http.Request) string {
+	if conf.BaseURL != "" {
+		return strings.TrimRight(conf.BaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// heroURL resolves a post's hero image against base.
+//
+// Heroes which are already absolute URLs are returned as is.
+func heroURL(base, hero string) string {
+	if strings.HasPrefix(hero, "http://") || strings.HasPrefix(hero, "https://") {
+		return hero
+	}
+	return absURL(base, hero)
+}
+
+// absURL joins the path p onto base.
+func absURL(base, p string) string {
+	return base + "/" + strings.TrimLeft(p, "/")
+}
+
+func writeXML(w http.ResponseWriter, r *http.Request, contentType string, v interface{}) {
+	buf, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(xml.Header))
+	w.Write(buf)
+}
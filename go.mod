@@ -3,9 +3,11 @@ module github.com/ldelossa/goblog
 go 1.16
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/fatih/color v1.10.0
 	github.com/go-git/go-git/v5 v5.3.0
 	github.com/rs/cors v1.7.0
+	github.com/yuin/goldmark v1.4.12
 	gopkg.in/yaml.v2 v2.3.0
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
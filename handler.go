@@ -10,20 +10,34 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-func WebHandler(appPaths []string) http.HandlerFunc {
+// WebHandler serves the web root, falling back to index.html
+// for the client side routes of the front-end application.
+//
+// When reload is not nil index.html carries a script which
+// reloads the page whenever reload is notified.
+func WebHandler(conf Config, reload *LiveReload) http.HandlerFunc {
 	const (
 		webPath  = "web"
 		blogPath = "blog"
 	)
+	// invalid app paths and post routes are reported by
+	// Validate before GoBlog begins serving.
+	appRoutes, _ := ParseAppRoutes(conf.AppPaths)
+	postRoutes, _ := conf.postRoutes()
+	index := path.Join(webPath, "index.html")
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
+		c := contentOf(r)
+
 		// any requested web files will expect to be
 		// hosted at root, WebFS however embeds files
 		// rooted at "web/" so add the web root.
@@ -35,102 +49,271 @@ func WebHandler(appPaths []string) http.HandlerFunc {
 
 		// if the incoming request is a path defined
 		// by the front-end application, serve index.html
-		for _, appPath := range appPaths {
-			if filepath.HasPrefix(r.URL.Path, appPath) {
-				p = path.Join(webPath, "index.html")
+		app, matched := appRoutes.Match(r.URL.Path)
+		if app {
+			p = index
+		}
+
+		// extension-less paths which aren't in the web root
+		// are assumed to be client side routes, unless an
+		// app path excludes them.
+		if !matched && path.Ext(p) == "" {
+			if _, err := fs.Stat(c.webFS, p); errors.Is(err, fs.ErrNotExist) {
+				p = index
+			}
+		}
+
+		// html documents may be rewritten per request, describing
+		// the post a deep link addresses, loading the live reload
+		// script and carrying the CSP nonce on their scripts and
+		// styles.
+		var edits []func([]byte) []byte
+		if p == index {
+			if post, ok := postForPath(postRoutes, c.publishedPosts(), r.URL.Path); ok {
+				base, url := baseURL(conf, r), requestURL(conf, r)
+				edits = append(edits, func(b []byte) []byte {
+					return injectPostMeta(b, post, base, url)
+				})
+			}
+			if reload != nil {
+				edits = append(edits, injectLiveReload)
 			}
 		}
+		if nonce := Nonce(r.Context()); nonce != "" && path.Ext(p) == ".html" {
+			edits = append(edits, func(b []byte) []byte {
+				return injectNonce(b, nonce)
+			})
+		}
+		if len(edits) > 0 {
+			serveEdited(w, r, p, edits)
+			return
+		}
 
-		f, err := WebFS.Open(p)
+		if servePrecompressed(w, r, c.webFS, p, c.webETags, c.webPrecompressed) {
+			return
+		}
+
+		f, fi, err := openAsset(c.webFS, p)
 		var fsErr *fs.PathError
 		switch {
 		case errors.As(err, &fsErr):
-			http.Error(w, "not found", http.StatusNotFound)
+			Error(w, r, "not found", http.StatusNotFound)
 			return
 		case err != nil:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			Error(w, r, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		defer f.Close()
 
-		io.Copy(w, f)
+		serveAsset(w, r, f, fi, c.webETags[p])
 	}
 }
 
+// serveEdited serves the html document at p in the web root after
+// applying edits to it in order.
+//
+// The edited document may differ on every request so it is
+// neither tagged nor cached.
+func serveEdited(w http.ResponseWriter, r *http.Request, p string, edits []func([]byte) []byte) {
+	f, _, err := openAsset(contentOf(r).webFS, p)
+	var fsErr *fs.PathError
+	switch {
+	case errors.As(err, &fsErr):
+		Error(w, r, "not found", http.StatusNotFound)
+		return
+	case err != nil:
+		Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, edit := range edits {
+		b = edit(b)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	serveBytes(w, r, "text/html; charset=utf-8", "", b)
+}
+
+// SummaryHandler serves the metadata of published posts
+// as a JSON array, newest first.
+//
+// The "tag" and "category" query parameters limit the
+// results to posts classified as such.
+//
+// Results may be paged through with the query parameters
+// documented on pagination. The number of posts matching
+// any date cursors is returned in the X-Total-Count header
+// and the next and previous pages are referenced in a Link
+// header.
 func SummaryHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
-		var lim int
-		var err error
-		tmp := r.URL.Query().Get("limit")
-		if tmp != "" {
-			lim, err = strconv.Atoi(tmp)
-			if err != nil {
-				http.Error(w, "could not parse limit param: "+err.Error(), http.StatusBadRequest)
-				return
-			}
+		pg, err := parsePagination(r)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		var summaries []Post
-		switch {
-		case lim == 0:
-			summaries = DSCache[:]
-		case lim > len(DSCache):
-			lim = len(DSCache)
-			summaries = DSCache[:lim]
-		default:
-			summaries = DSCache[:lim]
+		posts := contentOf(r).publishedPosts()
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			posts = posts.Filter(func(p Post) bool { return p.HasTag(tag) })
+		}
+		if category := r.URL.Query().Get("category"); category != "" {
+			posts = posts.Filter(func(p Post) bool { return strings.EqualFold(p.Category, category) })
 		}
 
+		res := pg.apply(posts)
+		w.Header().Set("X-Total-Count", strconv.Itoa(res.total))
+		if links := pg.links(r.URL, res); links != "" {
+			w.Header().Set("Link", links)
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		summaries := res.posts
+		if summaries == nil {
+			summaries = DateSortable{}
+		}
 		err = json.NewEncoder(w).Encode(summaries)
 		if err != nil {
-			http.Error(w, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+			Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
 		}
 	}
 
 }
 
-func PostsHandler() http.HandlerFunc {
+// TagsHandler serves every tag used by published posts
+// along with the number of posts carrying it.
+func TagsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(contentOf(r).publishedPosts().TagCounts())
+		if err != nil {
+			Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// parseLimit parses the optional "limit" query parameter.
+//
+// A zero value is returned if no limit was provided.
+func parseLimit(r *http.Request) (int, error) {
+	return nonNegativeParam(r.URL.Query(), "limit")
+}
+
+// limitPosts returns at most lim posts from the head of posts.
+//
+// A zero lim returns all posts.
+func limitPosts(posts DateSortable, lim int) DateSortable {
+	switch {
+	case lim == 0:
+		return posts[:]
+	case lim > len(posts):
+		return posts[:len(posts)]
+	default:
+		return posts[:lim]
+	}
+}
+
+// PostsHandler serves posts and their assets from PostsFS.
+//
+// Posts are served as markdown by default. Clients may ask
+// for the post rendered to HTML with the "format=html" query
+// parameter or by accepting "text/html".
+func PostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
 		post := r.URL.Path
 		post = strings.Trim(post, "/")
 		if post == "" || post == "posts" {
-			http.Error(w, "no asset provided in path", http.StatusBadRequest)
+			Error(w, r, "no asset provided in path", http.StatusBadRequest)
+			return
 		}
 
-		f, err := PostsFS.Open(post)
+		c := contentOf(r)
+
+		// scheduled posts are hidden until published.
+		if i, ok := c.posts.ByPath(post); ok && !c.posts[i].IsPublished(time.Now()) {
+			Error(w, r, "not found", http.StatusNotFound)
+			return
+		}
+
+		if filepath.Ext(post) != ".post" &&
+			servePrecompressed(w, r, c.postsFS, post, c.postsETags, c.postsPrecompressed) {
+			return
+		}
+
+		f, fi, err := openAsset(c.postsFS, post)
 		var fsErr *fs.PathError
 		switch {
 		case errors.As(err, &fsErr):
-			http.Error(w, "not found", http.StatusNotFound)
+			Error(w, r, "not found", http.StatusNotFound)
 			return
 		case err != nil:
-			http.Error(w, err.Error(), http.StatusBadGateway)
+			Error(w, r, err.Error(), http.StatusBadGateway)
 			return
 		}
+		defer f.Close()
 
 		// if its not a .post file, its an asset.
 		// so just serve it.
 		if filepath.Ext(post) != ".post" {
-			io.Copy(w, f)
+			serveAsset(w, r, f, fi, c.postsETags[post])
+			return
+		}
+
+		// the same .post file is served in multiple
+		// representations.
+		addVary(w.Header(), "Accept")
+
+		if wantsHTML(r) {
+			html, err := c.renderPost(post)
+			if err != nil {
+				Error(w, r, err.Error(), http.StatusBadGateway)
+				return
+			}
+			serveBytes(w, r, "text/html; charset=UTF-8", variantETag(c.postsETags[post], "html"), []byte(html))
 			return
 		}
 
 		var markdown Post
 		err = yaml.NewDecoder(f).Decode(&markdown)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadGateway)
+			Error(w, r, err.Error(), http.StatusBadGateway)
 			return
 		}
 
-		w.Header().Add("Content-Type", "text/markdown; charset=UTF-8")
-		w.Write([]byte(markdown.MarkDown.Value))
-		return
+		serveBytes(w, r, "text/markdown; charset=UTF-8", variantETag(c.postsETags[post], "md"), []byte(markdown.MarkDown.Value))
+	}
+}
+
+// wantsHTML determines if the client asked for a post
+// rendered to HTML instead of its markdown source.
+//
+// An explicit "format" query parameter takes precedence
+// over the Accept header.
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "markdown", "md":
+		return false
 	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
 }
@@ -0,0 +1,162 @@
+package goblog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Commit is the git commit of the source GoBlog was built
+// from.
+//
+// It may be set at build time with:
+//
+//	-ldflags "-X github.com/ldelossa/goblog.Commit=<sha>"
+//
+// which the publish command does with the commit checked out
+// in GoBlog's source.
+var Commit string
+
+// VersionInfo describes the running GoBlog binary.
+type VersionInfo struct {
+	BuildNum  int64  `json:"build_num"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	Posts     int    `json:"posts"`
+	Assets    int    `json:"assets"`
+}
+
+// NewVersionInfo describes the running binary and the
+// content embedded into it.
+func NewVersionInfo(conf Config) VersionInfo {
+	c := loaded()
+	assets := len(c.webETags)
+	for p := range c.postsETags {
+		if !isPost(p) {
+			assets++
+		}
+	}
+	return VersionInfo{
+		BuildNum:  conf.BuildNum,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+		Posts:     len(c.publishedPosts()),
+		Assets:    assets,
+	}
+}
+
+// Validate checks that the embedded content GoBlog serves
+// was loaded and is usable.
+func Validate() error {
+	if DSCache == nil {
+		return errors.New("DSCache was not loaded")
+	}
+	if Index == nil {
+		return errors.New("search index was not loaded")
+	}
+
+	f, err := ConfigFS.Open("config/config.yaml")
+	if err != nil {
+		return fmt.Errorf("could not open config: %w", err)
+	}
+	defer f.Close()
+	var conf Config
+	if err := yaml.NewDecoder(f).Decode(&conf); err != nil {
+		return fmt.Errorf("could not decode config: %w", err)
+	}
+	if err := conf.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := loaded().errorTemplate(); err != nil {
+		return err
+	}
+
+	fi, err := fs.Stat(WebFS, "web")
+	if err != nil {
+		return fmt.Errorf("could not stat web root: %w", err)
+	}
+	if !fi.IsDir() {
+		return errors.New("web root is not a directory")
+	}
+	return nil
+}
+
+// Readiness tracks whether GoBlog is ready to serve
+// traffic.
+type Readiness struct {
+	mu     sync.RWMutex
+	ready  bool
+	reason string
+}
+
+// Ready marks GoBlog as ready.
+func (r *Readiness) Ready() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.reason = true, ""
+}
+
+// NotReady marks GoBlog as not ready for the provided
+// reason.
+func (r *Readiness) NotReady(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.reason = false, reason
+}
+
+func (r *Readiness) state() (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ready, r.reason
+}
+
+// HealthHandler reports that the process is alive.
+func HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("ok\n"))
+	}
+}
+
+// ReadyHandler reports whether GoBlog is ready to serve
+// traffic, responding with a 503 when it is not.
+func ReadyHandler(ready *Readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		ok, reason := ready.state()
+		if !ok {
+			if reason == "" {
+				reason = "not ready"
+			}
+			Error(w, r, reason, http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.Write([]byte("ok\n"))
+	}
+}
+
+// VersionHandler serves the VersionInfo of the running
+// binary as JSON.
+func VersionHandler(conf Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := json.Marshal(NewVersionInfo(conf))
+		if err != nil {
+			Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(b, '\n'))
+	}
+}
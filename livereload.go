@@ -0,0 +1,144 @@
+package goblog
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LiveReloadPath is the path LiveReload.Handler is expected
+// to be served at, the injected reload script connects to
+// it.
+const LiveReloadPath = "/_goblog/livereload"
+
+// liveReloadPing is how often an idle event stream receives
+// a comment, keeping intermediaries from closing it.
+const liveReloadPing = 30 * time.Second
+
+// liveReloadScript reloads the page when the server sends a
+// reload event.
+const liveReloadScript = `<script>new EventSource("` + LiveReloadPath + `").addEventListener("reload", function () { location.reload(); });</script>`
+
+// LiveReload notifies connected browsers, over server-sent
+// events, that the content they display has changed.
+//
+// It is meant for previewing a blog while writing it and
+// should not be served in production.
+type LiveReload struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+	closed  bool
+	done    chan struct{}
+}
+
+// NewLiveReload returns a LiveReload with no connected
+// browsers.
+func NewLiveReload() *LiveReload {
+	return &LiveReload{
+		clients: map[chan struct{}]struct{}{},
+		done:    make(chan struct{}),
+	}
+}
+
+// Notify tells every connected browser to reload.
+func (lr *LiveReload) Notify() {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	for c := range lr.clients {
+		// a pending notification already covers this one.
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close ends every event stream, allowing a server to shut
+// down while browsers are connected.
+func (lr *LiveReload) Close() {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if !lr.closed {
+		lr.closed = true
+		close(lr.done)
+	}
+}
+
+func (lr *LiveReload) subscribe() (chan struct{}, bool) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if lr.closed {
+		return nil, false
+	}
+	c := make(chan struct{}, 1)
+	lr.clients[c] = struct{}{}
+	return c, true
+}
+
+func (lr *LiveReload) unsubscribe(c chan struct{}) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	delete(lr.clients, c)
+}
+
+// Handler streams a "reload" event to the browser every
+// time Notify is called.
+func (lr *LiveReload) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			Error(w, r, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		c, ok := lr.subscribe()
+		if !ok {
+			Error(w, r, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer lr.unsubscribe(c)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ping := time.NewTicker(liveReloadPing)
+		defer ping.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-lr.done:
+				return
+			case <-ping.C:
+				if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+					return
+				}
+			case <-c:
+				if _, err := w.Write([]byte("event: reload\ndata: {}\n\n")); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// injectLiveReload adds the reload script to the end of the
+// html document doc's body.
+func injectLiveReload(doc []byte) []byte {
+	script := []byte(liveReloadScript)
+	i := bytes.LastIndex(bytes.ToLower(doc), []byte("</body>"))
+	if i < 0 {
+		return append(doc, script...)
+	}
+	out := make([]byte, 0, len(doc)+len(script))
+	out = append(out, doc[:i]...)
+	out = append(out, script...)
+	return append(out, doc[i:]...)
+}
@@ -0,0 +1,170 @@
+package goblog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// md is the markdown renderer used whenever GoBlog
+// needs to turn a post's markdown body into HTML.
+//
+// Raw HTML found in markdown is omitted and links with
+// dangerous schemes (javascript:, vbscript:, etc) are
+// dropped, so the rendered output is safe to serve as is.
+//
+// Fenced code blocks are rendered with a "language-<lang>"
+// class for client-side syntax highlighters to hook into.
+var md = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		extension.Footnote,
+	),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithASTTransformers(
+			util.Prioritized(headingAnchors{}, 100),
+		),
+	),
+)
+
+// headingAnchors appends a self-referencing anchor link
+// to every heading which has an id.
+type headingAnchors struct{}
+
+func (headingAnchors) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != ast.KindHeading {
+			return ast.WalkContinue, nil
+		}
+		id, ok := n.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+		anchor := ast.NewLink()
+		anchor.Destination = append([]byte("#"), id.([]byte)...)
+		anchor.SetAttributeString("class", []byte("anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		n.AppendChild(n, anchor)
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// RenderMarkdown renders the provided markdown source
+// to HTML.
+func RenderMarkdown(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// plainText returns the text of the markdown src stripped of
+// its syntax, such as for excerpting. Raw HTML is dropped
+// while code is kept.
+func plainText(src string) string {
+	source := []byte(src)
+	doc := md.Parser().Parse(text.NewReader(source))
+	var b strings.Builder
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			// keep the text of adjacent blocks, and table cells,
+			// from running together.
+			if n.Type() == ast.TypeBlock {
+				b.WriteByte('\n')
+			}
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			// headingAnchors adds these, they aren't part of
+			// the post.
+			if class, ok := n.AttributeString("class"); ok && string(class.([]byte)) == "anchor" {
+				return ast.WalkSkipChildren, nil
+			}
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.AutoLink:
+			b.Write(n.Label(source))
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				b.Write(seg.Value(source))
+			}
+		case *ast.RawHTML, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// renderedPosts caches the rendered HTML of the posts of a
+// content snapshot keyed by their path. A snapshot's posts
+// never change so each only needs to be rendered once.
+type renderedPosts struct {
+	sync.RWMutex
+	html map[string]string
+}
+
+// RenderPost reads the post at path p from the posts being
+// served and renders its markdown body to HTML.
+//
+// Rendered posts are cached until LoadContent replaces the
+// posts.
+func RenderPost(p string) (string, error) {
+	return loaded().renderPost(p)
+}
+
+func (c *content) renderPost(p string) (string, error) {
+	c.rendered.RLock()
+	html, ok := c.rendered.html[p]
+	c.rendered.RUnlock()
+	if ok {
+		return html, nil
+	}
+
+	post, err := readPost(c.postsFS, p)
+	if err != nil {
+		return "", err
+	}
+	return c.renderMarkdown(post)
+}
+
+// renderMarkdown renders the markdown body of post, which
+// was already read from c, caching it like renderPost.
+func (c *content) renderMarkdown(post Post) (string, error) {
+	c.rendered.RLock()
+	html, ok := c.rendered.html[post.Path]
+	c.rendered.RUnlock()
+	if ok {
+		return html, nil
+	}
+
+	html, err := RenderMarkdown(post.MarkDown.Value)
+	if err != nil {
+		return "", err
+	}
+
+	c.rendered.Lock()
+	if c.rendered.html == nil {
+		c.rendered.html = map[string]string{}
+	}
+	c.rendered.html[post.Path] = html
+	c.rendered.Unlock()
+	return html, nil
+}
@@ -0,0 +1,128 @@
+package goblog
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// defaultPostRoutes are used when Config.PostRoutes is empty.
+var defaultPostRoutes = []string{"/post/:slug"}
+
+// postRoutes compiles the configured post routes.
+func (c Config) postRoutes() ([]Route, error) {
+	patterns := c.PostRoutes
+	if len(patterns) == 0 {
+		patterns = defaultPostRoutes
+	}
+	routes := make([]Route, 0, len(patterns))
+	for _, pattern := range patterns {
+		rt, err := ParseRoute(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !rt.hasParam("slug") {
+			return nil, fmt.Errorf("invalid post route %q: must contain a :slug parameter", pattern)
+		}
+		routes = append(routes, rt)
+	}
+	return routes, nil
+}
+
+// hasParam reports whether rt captures a parameter
+// called name.
+func (rt Route) hasParam(name string) bool {
+	for _, seg := range rt.segments {
+		if len(seg) > 0 && (seg[0] == ':' || seg[0] == '*') && seg[1:] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// postForPath returns the post of posts addressed by p
+// according to routes, if any.
+func postForPath(routes []Route, posts DateSortable, p string) (Post, bool) {
+	for _, rt := range routes {
+		params, ok := rt.Match(p)
+		if !ok {
+			continue
+		}
+		if i, ok := posts.BySlug(params["slug"]); ok {
+			return posts[i], true
+		}
+	}
+	return Post{}, false
+}
+
+var (
+	titleElement = regexp.MustCompile(`(?is)<title[^>]*>.*?</title>\s*`)
+	// postMeta matches the meta tags injectPostMeta writes.
+	postMeta  = regexp.MustCompile(`(?i)<meta\s+(name|property)=["']?(description|og:|twitter:|article:)[^>]*>\s*`)
+	headClose = regexp.MustCompile(`(?i)</head>`)
+)
+
+// injectPostMeta adds a title along with Open Graph and
+// Twitter card meta tags describing post to the head of
+// the html document doc.
+//
+// Any existing title element and meta tags of the same
+// kind are replaced. Documents without
+// a head are returned unchanged.
+func injectPostMeta(doc []byte, post Post, base, url string) []byte {
+	loc := headClose.FindIndex(doc)
+	if loc == nil {
+		return doc
+	}
+
+	var meta bytes.Buffer
+	tag := func(attr, key, value string) {
+		if value == "" {
+			return
+		}
+		fmt.Fprintf(&meta, "<meta %s=\"%s\" content=\"%s\">\n", attr, key, html.EscapeString(value))
+	}
+	var image string
+	if post.Hero != "" {
+		image = heroURL(base, post.Hero)
+	}
+	card := "summary"
+	if image != "" {
+		card = "summary_large_image"
+	}
+	tag("name", "description", post.Summary)
+	tag("property", "og:type", "article")
+	tag("property", "og:title", post.Title)
+	tag("property", "og:description", post.Summary)
+	tag("property", "og:url", url)
+	tag("property", "og:image", image)
+	if !post.Date.IsZero() {
+		tag("property", "article:published_time", post.Date.Format(time.RFC3339))
+	}
+	for _, t := range post.Tags {
+		tag("property", "article:tag", t)
+	}
+	tag("name", "twitter:card", card)
+	tag("name", "twitter:title", post.Title)
+	tag("name", "twitter:description", post.Summary)
+	tag("name", "twitter:image", image)
+
+	title := []byte("<title>" + html.EscapeString(post.Title) + "</title>")
+	var out bytes.Buffer
+	head := titleElement.ReplaceAllLiteral(doc[:loc[0]], nil)
+	head = postMeta.ReplaceAllLiteral(head, nil)
+	out.Write(head)
+	out.Write(title)
+	out.WriteString("\n")
+	out.Write(meta.Bytes())
+	out.Write(doc[loc[0]:])
+	return out.Bytes()
+}
+
+// requestURL returns the absolute URL of r.
+func requestURL(conf Config, r *http.Request) string {
+	return baseURL(conf, r) + r.URL.EscapedPath()
+}
@@ -0,0 +1,230 @@
+package goblog
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPageSize is the number of posts in a page when
+// paging by "page" without a "limit".
+const defaultPageSize = 10
+
+// maxPaginationOffset bounds the "limit" and "offset" paging
+// parameters and the offset a "page" addresses, keeping the
+// paging arithmetic from overflowing.
+const maxPaginationOffset = 1 << 20
+
+// pagination is the parsed form of the paging query
+// parameters accepted by SummaryHandler.
+//
+//	limit  - maximum number of posts to return
+//	offset - number of posts to skip
+//	page   - 1-based page number, pages are "limit" posts long
+//	before - only posts published before this RFC 3339 time
+//	after  - only posts published after this RFC 3339 time
+//
+// "offset" and "page" are mutually exclusive.
+type pagination struct {
+	limit  int
+	offset int
+	page   int
+	before time.Time
+	after  time.Time
+}
+
+// parsePagination parses and validates the paging query
+// parameters of r.
+func parsePagination(r *http.Request) (pagination, error) {
+	return parsePaginationQuery(r.URL.Query())
+}
+
+// parsePaginationQuery parses and validates the paging
+// parameters in q.
+func parsePaginationQuery(q url.Values) (pagination, error) {
+	var pg pagination
+	var err error
+
+	if pg.limit, err = nonNegativeParam(q, "limit"); err != nil {
+		return pg, err
+	}
+	if pg.limit > maxPaginationOffset {
+		return pg, fmt.Errorf("could not parse limit param: must be at most %d", maxPaginationOffset)
+	}
+	if pg.offset, err = nonNegativeParam(q, "offset"); err != nil {
+		return pg, err
+	}
+	if pg.offset > maxPaginationOffset {
+		return pg, fmt.Errorf("could not parse offset param: must be at most %d", maxPaginationOffset)
+	}
+	if pg.page, err = nonNegativeParam(q, "page"); err != nil {
+		return pg, err
+	}
+	if q.Get("page") != "" && pg.page == 0 {
+		return pg, fmt.Errorf("could not parse page param: pages start at 1")
+	}
+	if pg.page > 0 && q.Get("offset") != "" {
+		return pg, fmt.Errorf("offset and page params are mutually exclusive")
+	}
+	if pg.page > 0 {
+		if pg.limit == 0 {
+			pg.limit = defaultPageSize
+		}
+		if pg.page-1 > maxPaginationOffset/pg.limit {
+			return pg, fmt.Errorf("could not parse page param: must be at most %d", maxPaginationOffset/pg.limit+1)
+		}
+		pg.offset = (pg.page - 1) * pg.limit
+	}
+	if pg.before, err = timeParam(q, "before"); err != nil {
+		return pg, err
+	}
+	if pg.after, err = timeParam(q, "after"); err != nil {
+		return pg, err
+	}
+	return pg, nil
+}
+
+func nonNegativeParam(q url.Values, name string) (int, error) {
+	tmp := q.Get(name)
+	if tmp == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %s param: %w", name, err)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("could not parse %s param: must not be negative", name)
+	}
+	return v, nil
+}
+
+func timeParam(q url.Values, name string) (time.Time, error) {
+	tmp := q.Get(name)
+	if tmp == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, tmp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse %s param: %w", name, err)
+	}
+	return t, nil
+}
+
+// page is the result of applying a pagination to posts.
+type page struct {
+	posts DateSortable
+	// total is the number of posts matching the date cursors.
+	total int
+	// older and newer report whether posts exist beyond
+	// either end of the page, regardless of date cursors.
+	older, newer bool
+}
+
+// apply pages through posts.
+//
+// When paging with only an "after" cursor the posts closest
+// to the cursor are returned, allowing clients to walk
+// backwards through newer posts.
+func (pg pagination) apply(posts DateSortable) page {
+	// posts are sorted newest first, so the "before" cursor
+	// trims the head and the "after" cursor the tail.
+	start, end := 0, len(posts)
+	if !pg.before.IsZero() {
+		for start < end && !posts[start].Date.Before(pg.before) {
+			start++
+		}
+	}
+	if !pg.after.IsZero() {
+		for end > start && !posts[end-1].Date.After(pg.after) {
+			end--
+		}
+	}
+	total := end - start
+
+	// comparisons are made against the remaining posts so
+	// large offsets and limits can't overflow.
+	first, last := end, end
+	if pg.offset < total {
+		first = start + pg.offset
+	}
+	switch {
+	case first == end:
+	case pg.limit == 0:
+	case !pg.after.IsZero() && pg.before.IsZero() && pg.offset == 0 && pg.page == 0:
+		if pg.limit < end-first {
+			first = end - pg.limit
+		}
+	case pg.limit < end-first:
+		last = first + pg.limit
+	}
+	return page{
+		posts: posts[first:last],
+		total: total,
+		older: last < len(posts),
+		newer: first > 0,
+	}
+}
+
+// links returns an RFC 8288 Link header value referencing
+// the next and previous pages of pg, if any.
+func (pg pagination) links(u *url.URL, p page) string {
+	if pg.limit == 0 {
+		return ""
+	}
+	var links []string
+	link := func(rel string, set map[string]string) {
+		next := *u
+		q := next.Query()
+		for k, v := range set {
+			if v == "" {
+				q.Del(k)
+				continue
+			}
+			q.Set(k, v)
+		}
+		next.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, next.String(), rel))
+	}
+
+	switch {
+	case pg.page > 0:
+		if pg.offset+len(p.posts) < p.total {
+			link("next", map[string]string{"page": strconv.Itoa(pg.page + 1)})
+		}
+		if pg.page > 1 {
+			link("prev", map[string]string{"page": strconv.Itoa(pg.page - 1)})
+		}
+	case pg.offset > 0 || (pg.before.IsZero() && pg.after.IsZero()):
+		if pg.offset+len(p.posts) < p.total {
+			link("next", map[string]string{"offset": strconv.Itoa(pg.offset + pg.limit)})
+		}
+		if pg.offset > 0 {
+			prev := pg.offset - pg.limit
+			if prev < 0 {
+				prev = 0
+			}
+			link("prev", map[string]string{"offset": strconv.Itoa(prev)})
+		}
+	case len(p.posts) > 0:
+		// cursor based paging, the next page is everything
+		// older than the oldest post returned and the previous
+		// page everything newer than the newest.
+		if p.older {
+			link("next", map[string]string{
+				"before": p.posts[len(p.posts)-1].Date.Format(time.RFC3339Nano),
+				"after":  "",
+			})
+		}
+		if p.newer {
+			link("prev", map[string]string{
+				"after":  p.posts[0].Date.Format(time.RFC3339Nano),
+				"before": "",
+			})
+		}
+	}
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,115 @@
+package goblog
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParsePaginationQuery(t *testing.T) {
+	maxInt := strconv.Itoa(int(^uint(0) >> 1))
+	tt := []struct {
+		name   string
+		query  string
+		want   pagination
+		hasErr bool
+	}{
+		{name: "empty", query: ""},
+		{name: "limit", query: "limit=5", want: pagination{limit: 5}},
+		{name: "offset", query: "offset=5&limit=2", want: pagination{limit: 2, offset: 5}},
+		{name: "page default size", query: "page=3", want: pagination{limit: defaultPageSize, page: 3, offset: 2 * defaultPageSize}},
+		{name: "page with limit", query: "page=2&limit=4", want: pagination{limit: 4, page: 2, offset: 4}},
+		{name: "last page", query: "page=" + strconv.Itoa(maxPaginationOffset/2+1) + "&limit=2", want: pagination{limit: 2, page: maxPaginationOffset/2 + 1, offset: maxPaginationOffset}},
+		{name: "page zero", query: "page=0", hasErr: true},
+		{name: "negative limit", query: "limit=-1", hasErr: true},
+		{name: "offset and page", query: "offset=1&page=1", hasErr: true},
+		{name: "max limit", query: "limit=" + strconv.Itoa(maxPaginationOffset), want: pagination{limit: maxPaginationOffset}},
+		{name: "limit too large", query: "limit=" + strconv.Itoa(maxPaginationOffset+1), hasErr: true},
+		{name: "limit overflow", query: "offset=2&limit=" + maxInt, hasErr: true},
+		{name: "offset too large", query: "offset=" + strconv.Itoa(maxPaginationOffset+1), hasErr: true},
+		{name: "offset overflow", query: "offset=" + maxInt + "&before=2021-01-01T00:00:00Z", hasErr: true},
+		{name: "page too large", query: "page=" + strconv.Itoa(maxPaginationOffset/2+2) + "&limit=2", hasErr: true},
+		{name: "page overflow", query: "page=" + maxInt + "&limit=2", hasErr: true},
+		{name: "page overflow default size", query: "page=4611686018427387905", hasErr: true},
+		{name: "bad before", query: "before=yesterday", hasErr: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parsePaginationQuery(q)
+			if tc.hasErr {
+				if err == nil {
+					t.Fatalf("got %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPaginationApply(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2021, 1, n, 0, 0, 0, 0, time.UTC)
+	}
+	// newest first, as DSCache is sorted.
+	var posts DateSortable
+	for d := 10; d >= 1; d-- {
+		posts = append(posts, Post{Slug: strconv.Itoa(d), Date: day(d)})
+	}
+	maxInt := int(^uint(0) >> 1)
+
+	tt := []struct {
+		name         string
+		pg           pagination
+		slugs        []string
+		total        int
+		older, newer bool
+	}{
+		{name: "all", pg: pagination{}, slugs: []string{"10", "9", "8", "7", "6", "5", "4", "3", "2", "1"}, total: 10},
+		{name: "limit", pg: pagination{limit: 2}, slugs: []string{"10", "9"}, total: 10, older: true},
+		{name: "offset", pg: pagination{limit: 2, offset: 8}, slugs: []string{"2", "1"}, total: 10, newer: true},
+		{name: "offset at end", pg: pagination{limit: 2, offset: 10}, total: 10, newer: true},
+		{name: "offset past end", pg: pagination{limit: 2, offset: 11}, total: 10, newer: true},
+		{name: "page", pg: pagination{limit: 3, page: 2, offset: 3}, slugs: []string{"7", "6", "5"}, total: 10, older: true, newer: true},
+		{name: "before", pg: pagination{limit: 2, before: day(5)}, slugs: []string{"4", "3"}, total: 4, older: true, newer: true},
+		{name: "after", pg: pagination{limit: 2, after: day(5)}, slugs: []string{"7", "6"}, total: 5, older: true, newer: true},
+		{name: "before and after", pg: pagination{before: day(8), after: day(5)}, slugs: []string{"7", "6"}, total: 2, older: true, newer: true},
+		{name: "max offset", pg: pagination{limit: 2, offset: maxInt}, total: 10, newer: true},
+		{name: "max offset with cursor", pg: pagination{offset: maxInt, before: day(5)}, total: 4, newer: true},
+		{name: "max limit", pg: pagination{limit: maxInt, offset: 2}, slugs: []string{"8", "7", "6", "5", "4", "3", "2", "1"}, total: 10, newer: true},
+		{name: "max limit after", pg: pagination{limit: maxInt, after: day(8)}, slugs: []string{"10", "9"}, total: 2, older: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.pg.apply(posts)
+			var slugs []string
+			for _, p := range got.posts {
+				slugs = append(slugs, p.Slug)
+			}
+			if len(slugs) != len(tc.slugs) {
+				t.Fatalf("got posts %v, want %v", slugs, tc.slugs)
+			}
+			for i := range slugs {
+				if slugs[i] != tc.slugs[i] {
+					t.Fatalf("got posts %v, want %v", slugs, tc.slugs)
+				}
+			}
+			if got.total != tc.total {
+				t.Errorf("got total %d, want %d", got.total, tc.total)
+			}
+			if got.older != tc.older || got.newer != tc.newer {
+				t.Errorf("got older %v newer %v, want older %v newer %v", got.older, got.newer, tc.older, tc.newer)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Package metrics implements a small subset of Prometheus
+// instrumentation, enough to expose counters, gauges and
+// histograms in the text exposition format without pulling
+// in a client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the default histogram buckets, in seconds,
+// suitable for http request latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector is a metric family which can write itself
+// in the text exposition format.
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds a set of metric families.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Handler serves every registered metric family in the
+// Prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		collectors := make([]collector, len(r.collectors))
+		copy(collectors, r.collectors)
+		r.mu.Unlock()
+		sort.Slice(collectors, func(i, j int) bool {
+			return collectors[i].name() < collectors[j].name()
+		})
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range collectors {
+			c.write(w)
+		}
+	})
+}
+
+// labelSet renders label names and values as a
+// "{name="value",...}" string.
+func labelSet(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i := range names {
+		pairs[i] = names[i] + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// CounterVec is a family of monotonically increasing
+// counters partitioned by labels.
+type CounterVec struct {
+	n, help string
+	labels  []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	keys   map[string][]string
+}
+
+// NewCounterVec creates and registers a CounterVec.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		n:      name,
+		help:   help,
+		labels: labels,
+		values: map[string]float64{},
+		keys:   map[string][]string{},
+	}
+	r.register(c)
+	return c
+}
+
+// Add adds v to the counter with the provided label values.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.keys[key]; !ok {
+		c.keys[key] = labelValues
+	}
+	c.values[key] += v
+}
+
+// Inc increments the counter with the provided label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) name() string { return c.n }
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.n, c.help, "counter")
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.n, labelSet(c.labels, c.keys[k]), formatFloat(c.values[k]))
+	}
+}
+
+// GaugeFunc is a gauge whose value is computed on
+// every scrape.
+type GaugeFunc struct {
+	n, help string
+	labels  []string
+	values  []string
+	fn      func() float64
+}
+
+// NewGaugeFunc creates and registers a GaugeFunc with
+// constant labels.
+func (r *Registry) NewGaugeFunc(name, help string, labels map[string]string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{n: name, help: help, fn: fn}
+	for k := range labels {
+		g.labels = append(g.labels, k)
+	}
+	sort.Strings(g.labels)
+	for _, k := range g.labels {
+		g.values = append(g.values, labels[k])
+	}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) name() string { return g.n }
+
+func (g *GaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.n, g.help, "gauge")
+	fmt.Fprintf(w, "%s%s %s\n", g.n, labelSet(g.labels, g.values), formatFloat(g.fn()))
+}
+
+// HistogramVec is a family of histograms partitioned
+// by labels.
+type HistogramVec struct {
+	n, help string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec creates and registers a HistogramVec.
+//
+// If buckets is nil DefBuckets are used.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &HistogramVec{
+		n:       name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*histogram{},
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the histogram with the provided
+// label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) name() string { return h.n }
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.n, h.help, "histogram")
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	names := append(append([]string{}, h.labels...), "le")
+	for _, k := range keys {
+		s := h.series[k]
+		for i, b := range h.buckets {
+			values := append(append([]string{}, s.labelValues...), formatFloat(b))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.n, labelSet(names, values), s.counts[i])
+		}
+		values := append(append([]string{}, s.labelValues...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.n, labelSet(names, values), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.n, labelSet(h.labels, s.labelValues), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.n, labelSet(h.labels, s.labelValues), s.count)
+	}
+}
+
+// ResponseRecorder wraps an http.ResponseWriter recording
+// the status code and number of bytes written.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int64
+}
+
+func (rr *ResponseRecorder) WriteHeader(status int) {
+	if rr.Status == 0 {
+		rr.Status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *ResponseRecorder) Write(b []byte) (int, error) {
+	if rr.Status == 0 {
+		rr.Status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.Bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (rr *ResponseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (rr *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// StatusClass returns the class of an http status code,
+// such as "2xx".
+func StatusClass(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// HTTPMetrics are the metric families recorded by Instrument.
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+	bytes    *CounterVec
+}
+
+// NewHTTPMetrics creates and registers the metric families
+// used to instrument http handlers, prefixing their names
+// with namespace.
+func (r *Registry) NewHTTPMetrics(namespace string) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounterVec(namespace+"_http_requests_total",
+			"Total number of http requests by handler and status class.", "handler", "code"),
+		duration: r.NewHistogramVec(namespace+"_http_request_duration_seconds",
+			"Latency of http requests by handler.", nil, "handler"),
+		bytes: r.NewCounterVec(namespace+"_http_response_bytes_total",
+			"Total number of response body bytes served by handler.", "handler"),
+	}
+}
+
+// Instrument wraps h, recording a request count, latency
+// and bytes served under the provided handler label.
+//
+// If observe is not nil it is called with the request and
+// recorded response once h returns.
+func (m *HTTPMetrics) Instrument(handler string, h http.Handler, observe func(*http.Request, *ResponseRecorder)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rr := &ResponseRecorder{ResponseWriter: w}
+		h.ServeHTTP(rr, r)
+		m.duration.Observe(time.Since(start).Seconds(), handler)
+		m.requests.Inc(handler, StatusClass(rr.Status))
+		m.bytes.Add(float64(rr.Bytes), handler)
+		if observe != nil {
+			observe(r, rr)
+		}
+	})
+}
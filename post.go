@@ -1,6 +1,9 @@
 package goblog
 
 import (
+	"path"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -29,11 +32,122 @@ func (t DateSortable) Swap(i, j int) {
 type Post struct {
 	// internally used; the path in the embed.FS where the
 	// contents the post can be read.
-	Path    string    `json:"path" yaml:"-"`
+	Path string `json:"path" yaml:"-"`
+	// internally used; the post's file name without its
+	// extension, used to address the post in URLs.
+	Slug    string    `json:"slug" yaml:"-"`
 	Hero    string    `json:"hero" yaml:"hero"`
 	Title   string    `json:"title" yaml:"title"`
 	Summary string    `json:"summary" yaml:"summary"`
 	Date    time.Time `json:"date" yaml:"date"`
+	// Tags and Category classify the post, allowing
+	// topic pages to be built.
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Category string   `json:"category,omitempty" yaml:"category,omitempty"`
+	// Aliases are old URL paths, such as those the post was
+	// published under before being renamed, which redirect
+	// to the post.
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// PublishAt schedules the post, it is hidden until this
+	// moment passes. If zero the post is published as soon
+	// as it is embedded.
+	PublishAt time.Time `json:"-" yaml:"publish_at,omitempty"`
 	// the markdown body of the blog post.
 	MarkDown yaml.Node `json:"-" yaml:"mark_down,omitempty"`
 }
+
+// IsPublished reports whether the post is visible at now.
+func (p Post) IsPublished(now time.Time) bool {
+	return p.PublishAt.IsZero() || !p.PublishAt.After(now)
+}
+
+// HasTag reports whether the post is tagged with tag.
+//
+// Tags are matched case insensitively.
+func (p Post) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the posts for which keep returns true,
+// preserving their order.
+func (t DateSortable) Filter(keep func(Post) bool) DateSortable {
+	filtered := DateSortable{}
+	for _, post := range t {
+		if keep(post) {
+			filtered = append(filtered, post)
+		}
+	}
+	return filtered
+}
+
+// TagCount is the number of posts tagged with Tag.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TagCounts counts the posts carrying each tag, ordered
+// by count and then tag name.
+//
+// Tags differing only in case are counted together under
+// the first spelling encountered.
+func (t DateSortable) TagCounts() []TagCount {
+	idx := map[string]int{}
+	counts := []TagCount{}
+	for _, post := range t {
+		for _, tag := range post.Tags {
+			key := strings.ToLower(tag)
+			i, ok := idx[key]
+			if !ok {
+				i = len(counts)
+				idx[key] = i
+				counts = append(counts, TagCount{Tag: tag})
+			}
+			counts[i].Count++
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+	return counts
+}
+
+// SlugFromPath derives a post's slug from its path.
+func SlugFromPath(p string) string {
+	return strings.TrimSuffix(path.Base(p), path.Ext(p))
+}
+
+// BySlug returns the index of the post with the provided
+// slug.
+func (t DateSortable) BySlug(slug string) (int, bool) {
+	for i, post := range t {
+		if post.Slug == slug {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// ByPath returns the index of the post at path p.
+func (t DateSortable) ByPath(p string) (int, bool) {
+	for i, post := range t {
+		if post.Path == p {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// isPost reports whether the file at p is a post rather
+// than an asset.
+func isPost(p string) bool {
+	return path.Ext(p) == ".post"
+}
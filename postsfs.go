@@ -10,13 +10,23 @@ import (
 )
 
 //go:embed posts/*
-var PostsFS embed.FS
+var embeddedPosts embed.FS
+
+// PostsFS holds published posts and their assets, rooted
+// at "posts/".
+//
+// It is the posts embedded when GoBlog was built unless
+// replaced by LoadContent.
+var PostsFS fs.FS = embeddedPosts
 
 // DSCache is a DateSortable slice of posts
 // which only contains the metadata of a post.
 //
 // This allows us to quickly read out date ordered posts
 // without walking the embeded filesystem more then once.
+//
+// DSCache holds every embedded post including those scheduled
+// for the future, see Published for the posts being served.
 var DSCache DateSortable
 
 func init() {
@@ -25,21 +35,33 @@ func init() {
 	if err != nil {
 		panic("could not create DSCache: " + err.Error())
 	}
+	Index, err = NewSearchIndex(DSCache)
+	if err != nil {
+		panic("could not create search index: " + err.Error())
+	}
 }
 
 func NewDSCache() (DateSortable, error) {
+	return newDSCache(PostsFS)
+}
+
+func newDSCache(fsys fs.FS) (DateSortable, error) {
 	sorted := DateSortable{}
-	err := fs.WalkDir(PostsFS, "posts", func(p string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, "posts", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 		if d.IsDir() {
 			return nil
 		}
 		if filepath.Ext(d.Name()) != ".post" {
 			return nil
 		}
-		f, err := PostsFS.Open(p)
+		f, err := fsys.Open(p)
 		if err != nil {
 			return err
 		}
+		defer f.Close()
 
 		var post Post
 		err = yaml.NewDecoder(f).Decode(&post)
@@ -54,11 +76,16 @@ func NewDSCache() (DateSortable, error) {
 		}
 
 		sorted = append(sorted, Post{
-			Path:    p,
-			Title:   post.Title,
-			Summary: post.Summary,
-			Date:    post.Date,
-			Hero:    post.Hero,
+			Path:      p,
+			Slug:      SlugFromPath(p),
+			Title:     post.Title,
+			Summary:   post.Summary,
+			Date:      post.Date,
+			Hero:      post.Hero,
+			Tags:      post.Tags,
+			Category:  post.Category,
+			Aliases:   post.Aliases,
+			PublishAt: post.PublishAt,
 		})
 		return nil
 	})
@@ -70,3 +97,26 @@ func NewDSCache() (DateSortable, error) {
 	}
 	return sorted, nil
 }
+
+// ReadPost decodes the post found at path p in PostsFS,
+// including its markdown body.
+func ReadPost(p string) (Post, error) {
+	return readPost(PostsFS, p)
+}
+
+func readPost(fsys fs.FS, p string) (Post, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return Post{}, err
+	}
+	defer f.Close()
+
+	var post Post
+	err = yaml.NewDecoder(f).Decode(&post)
+	if err != nil {
+		return Post{}, err
+	}
+	post.Path = p
+	post.Slug = SlugFromPath(p)
+	return post, nil
+}
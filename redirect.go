@@ -0,0 +1,293 @@
+package goblog
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Redirect match types.
+const (
+	// RedirectExact redirects requests for exactly From.
+	RedirectExact = "exact"
+	// RedirectPrefix redirects requests for From and any
+	// path beneath it, appending the remainder of the path
+	// to To.
+	RedirectPrefix = "prefix"
+	// RedirectPattern redirects requests matching From as a
+	// Route pattern. Parameters captured by the pattern may
+	// be referenced by segments of To, such as
+	// "/blog/:year/:slug" to "/post/:slug".
+	RedirectPattern = "pattern"
+)
+
+// maxRedirectHops bounds the length of redirect chains
+// allowed by NewRedirects.
+const maxRedirectHops = 10
+
+// Redirect sends requests for From to To.
+type Redirect struct {
+	From string `json:"from" yaml:"from"`
+	// To is a path or an absolute URL.
+	To string `json:"to" yaml:"to"`
+	// Match is one of "exact", "prefix" or "pattern". If
+	// empty "exact" is assumed.
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Code is the http status code of the redirect, one of
+	// 301, 302 or 308. If zero 301 is assumed.
+	Code int `json:"code,omitempty" yaml:"code,omitempty"`
+}
+
+// normalize fills in defaults and validates rd.
+func (rd Redirect) normalize() (Redirect, error) {
+	if rd.Match == "" {
+		rd.Match = RedirectExact
+	}
+	if rd.Code == 0 {
+		rd.Code = http.StatusMovedPermanently
+	}
+	switch {
+	case !strings.HasPrefix(rd.From, "/"):
+		return rd, fmt.Errorf("invalid redirect from %q: must begin with '/'", rd.From)
+	case rd.To == "":
+		return rd, fmt.Errorf("invalid redirect from %q: no destination", rd.From)
+	case strings.HasPrefix(rd.To, "//") || strings.HasPrefix(rd.To, "/\\"):
+		return rd, fmt.Errorf("invalid redirect from %q: destination %q is a protocol relative url, use an absolute url", rd.From, rd.To)
+	}
+	switch rd.Match {
+	case RedirectExact, RedirectPrefix, RedirectPattern:
+	default:
+		return rd, fmt.Errorf("invalid redirect from %q: unknown match %q, must be one of %q, %q or %q",
+			rd.From, rd.Match, RedirectExact, RedirectPrefix, RedirectPattern)
+	}
+	switch rd.Code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusPermanentRedirect:
+	default:
+		return rd, fmt.Errorf("invalid redirect from %q: code %d must be one of 301, 302 or 308", rd.From, rd.Code)
+	}
+	return rd, nil
+}
+
+// compiledRedirect is a prefix or pattern Redirect.
+type compiledRedirect struct {
+	Redirect
+	route Route
+}
+
+// Redirects is a compiled set of Redirect rules along with
+// the aliases of posts.
+type Redirects struct {
+	exact map[string]Redirect
+	// scheduled holds when the post each alias of a
+	// scheduled post redirects to is published, the alias
+	// is ignored until then.
+	scheduled map[string]time.Time
+	// prefix and pattern rules in the order configured.
+	rules []compiledRedirect
+}
+
+// NewRedirects compiles rules and the aliases of posts,
+// returning an error if any rule is invalid, two rules
+// claim the same path or following the rules would loop.
+//
+// Exact rules and aliases take precedence over prefix and
+// pattern rules, which are tried in order. Aliases of
+// scheduled posts only redirect once the post is published,
+// though they claim their path from the start.
+func NewRedirects(rules []Redirect, posts DateSortable) (*Redirects, error) {
+	rs := &Redirects{exact: map[string]Redirect{}, scheduled: map[string]time.Time{}}
+	claim := func(rd Redirect) error {
+		if prev, ok := rs.exact[rd.From]; ok {
+			return fmt.Errorf("redirect from %q to %q conflicts with redirect to %q", rd.From, rd.To, prev.To)
+		}
+		rs.exact[rd.From] = rd
+		return nil
+	}
+
+	for _, rd := range rules {
+		rd, err := rd.normalize()
+		if err != nil {
+			return nil, err
+		}
+		switch rd.Match {
+		case RedirectExact:
+			if err := claim(rd); err != nil {
+				return nil, err
+			}
+		case RedirectPrefix:
+			rs.rules = append(rs.rules, compiledRedirect{Redirect: rd})
+		case RedirectPattern:
+			rt, err := ParseRoute(rd.From)
+			if err != nil {
+				return nil, fmt.Errorf("invalid redirect: %w", err)
+			}
+			for _, seg := range strings.Split(rd.To, "/") {
+				if name := paramName(seg); name != "" && !rt.hasParam(name) {
+					return nil, fmt.Errorf("invalid redirect from %q: %q is not a parameter of the pattern", rd.From, seg)
+				}
+			}
+			rs.rules = append(rs.rules, compiledRedirect{Redirect: rd, route: rt})
+		}
+	}
+
+	for _, post := range posts {
+		for _, alias := range post.Aliases {
+			rd, err := Redirect{From: alias, To: "/" + post.Path}.normalize()
+			if err != nil {
+				return nil, fmt.Errorf("invalid alias of post %v: %w", post.Path, err)
+			}
+			if err := claim(rd); err != nil {
+				return nil, fmt.Errorf("invalid alias of post %v: %w", post.Path, err)
+			}
+			if !post.PublishAt.IsZero() {
+				rs.scheduled[alias] = post.PublishAt
+			}
+		}
+	}
+
+	// check the redirects as they are before any scheduled
+	// post is published and after each one is.
+	if err := rs.checkLoops(time.Time{}); err != nil {
+		return nil, err
+	}
+	for _, at := range rs.scheduled {
+		if err := rs.checkLoops(at); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// paramName returns the name of the parameter seg refers
+// to, if any.
+func paramName(seg string) string {
+	if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+		return seg[1:]
+	}
+	return ""
+}
+
+// Lookup returns the destination and status code of the
+// redirect matching the path p.
+func (rs *Redirects) Lookup(p string) (string, int, bool) {
+	return rs.lookup(p, time.Now())
+}
+
+// lookup is Lookup as of now, which decides whether the
+// aliases of scheduled posts apply.
+func (rs *Redirects) lookup(p string, now time.Time) (string, int, bool) {
+	if rd, ok := rs.exact[p]; ok && !rs.scheduled[p].After(now) {
+		return rd.To, rd.Code, true
+	}
+	for _, rd := range rs.rules {
+		switch rd.Match {
+		case RedirectPrefix:
+			from := strings.TrimSuffix(rd.From, "/")
+			if p != rd.From && p != from && !strings.HasPrefix(p, from+"/") {
+				continue
+			}
+			// the remainder is joined to To with a single slash,
+			// "/blog//host" must not redirect to "//host".
+			rest := strings.TrimLeft(strings.TrimPrefix(p, from), "/")
+			if rest == "" {
+				return rd.To, rd.Code, true
+			}
+			return localPath(strings.TrimSuffix(rd.To, "/") + "/" + rest), rd.Code, true
+		case RedirectPattern:
+			params, ok := rd.route.Match(p)
+			if !ok {
+				continue
+			}
+			segs := strings.Split(rd.To, "/")
+			for i, seg := range segs {
+				if name := paramName(seg); name != "" {
+					segs[i] = params[name]
+				}
+			}
+			return localPath(strings.Join(segs, "/")), rd.Code, true
+		}
+	}
+	return "", 0, false
+}
+
+// localPath collapses the leading slashes and backslashes of
+// a path built from a request, browsers read "//host" and
+// "/\host" as another host. Absolute urls are returned as is.
+func localPath(to string) string {
+	if !strings.HasPrefix(to, "/") {
+		return to
+	}
+	return "/" + strings.TrimLeft(to, "/\\")
+}
+
+// checkLoops follows the redirects of every rule as of now,
+// failing when a chain revisits a path or grows too long.
+func (rs *Redirects) checkLoops(now time.Time) error {
+	var starts []string
+	for from := range rs.exact {
+		starts = append(starts, from)
+	}
+	for _, rd := range rs.rules {
+		switch rd.Match {
+		case RedirectPrefix:
+			starts = append(starts, rd.From)
+		case RedirectPattern:
+			// fill every parameter with a placeholder segment.
+			segs := strings.Split(rd.From, "/")
+			for i, seg := range segs {
+				if paramName(seg) != "" || seg == "*" {
+					segs[i] = "x"
+				}
+			}
+			starts = append(starts, strings.Join(segs, "/"))
+		}
+	}
+
+	for _, start := range starts {
+		chain := []string{start}
+		seen := map[string]bool{start: true}
+		p := start
+		for {
+			to, _, ok := rs.lookup(p, now)
+			if !ok {
+				break
+			}
+			// only redirects to local paths can loop.
+			if !strings.HasPrefix(to, "/") || strings.HasPrefix(to, "//") {
+				break
+			}
+			if i := strings.IndexAny(to, "?#"); i >= 0 {
+				to = to[:i]
+			}
+			chain = append(chain, to)
+			if seen[to] {
+				return fmt.Errorf("redirect loop: %v", strings.Join(chain, " -> "))
+			}
+			if len(chain) > maxRedirectHops {
+				return fmt.Errorf("redirect chain from %q exceeds %d hops", start, maxRedirectHops)
+			}
+			seen[to] = true
+			p = to
+		}
+	}
+	return nil
+}
+
+// Handler wraps h, redirecting requests which match a rule.
+//
+// The request's query string is carried over unless the
+// destination provides its own.
+func (rs *Redirects) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		to, code, ok := rs.Lookup(r.URL.Path)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.RawQuery != "" && !strings.Contains(to, "?") {
+			to += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, to, code)
+	})
+}
@@ -0,0 +1,172 @@
+package goblog
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedirectsLookup(t *testing.T) {
+	rules := []Redirect{
+		{From: "/about-me", To: "/about"},
+		{From: "/old", To: "https://example.com/new", Code: http.StatusFound},
+		{From: "/blog", To: "/posts", Match: RedirectPrefix},
+		{From: "/docs/", To: "/manual/", Match: RedirectPrefix, Code: http.StatusPermanentRedirect},
+		{From: "/y/:year/:slug", To: "/post/:slug", Match: RedirectPattern},
+		{From: "/files/*rest", To: "/static/*rest", Match: RedirectPattern},
+		{From: "/go/*rest", To: "/*rest", Match: RedirectPattern},
+		{From: "/blog/special", To: "/special"},
+	}
+	posts := DateSortable{{Path: "posts/hello.post", Aliases: []string{"/hello"}}}
+	rs, err := NewRedirects(rules, posts)
+	if err != nil {
+		t.Fatalf("NewRedirects: %v", err)
+	}
+
+	tt := []struct {
+		path     string
+		want     string
+		wantCode int
+		noMatch  bool
+	}{
+		{path: "/about-me", want: "/about", wantCode: http.StatusMovedPermanently},
+		{path: "/about-me/", noMatch: true},
+		{path: "/old", want: "https://example.com/new", wantCode: http.StatusFound},
+		{path: "/hello", want: "/posts/hello.post", wantCode: http.StatusMovedPermanently},
+		{path: "/blog", want: "/posts", wantCode: http.StatusMovedPermanently},
+		{path: "/blog/", want: "/posts", wantCode: http.StatusMovedPermanently},
+		{path: "/blog/a/b", want: "/posts/a/b", wantCode: http.StatusMovedPermanently},
+		{path: "/blogs", noMatch: true},
+		// exact rules take precedence over prefix rules.
+		{path: "/blog/special", want: "/special", wantCode: http.StatusMovedPermanently},
+		{path: "/docs", want: "/manual/", wantCode: http.StatusPermanentRedirect},
+		{path: "/docs/intro", want: "/manual/intro", wantCode: http.StatusPermanentRedirect},
+		{path: "/y/2021/hello", want: "/post/hello", wantCode: http.StatusMovedPermanently},
+		{path: "/files/a/b.css", want: "/static/a/b.css", wantCode: http.StatusMovedPermanently},
+		{path: "/nothing", noMatch: true},
+		// paths which would redirect to another host.
+		{path: "/blog//evil.com", want: "/posts/evil.com", wantCode: http.StatusMovedPermanently},
+		{path: "/go//evil.com", want: "/evil.com", wantCode: http.StatusMovedPermanently},
+		{path: "/go/\\evil.com", want: "/evil.com", wantCode: http.StatusMovedPermanently},
+	}
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			got, code, ok := rs.Lookup(tc.path)
+			if ok == tc.noMatch {
+				t.Fatalf("got match %v, want %v", ok, !tc.noMatch)
+			}
+			if got != tc.want || code != tc.wantCode {
+				t.Errorf("got %q, %d, want %q, %d", got, code, tc.want, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestNewRedirectsInvalid(t *testing.T) {
+	// chain builds a chain of n exact redirects.
+	chain := func(n int) []Redirect {
+		var rules []Redirect
+		for i := 0; i < n; i++ {
+			rules = append(rules, Redirect{From: "/" + strings.Repeat("a", i+1), To: "/" + strings.Repeat("a", i+2)})
+		}
+		return rules
+	}
+
+	tt := []struct {
+		name  string
+		rules []Redirect
+		posts DateSortable
+		// wantErr is a substring of the expected error, empty
+		// when the redirects are valid.
+		wantErr string
+	}{
+		{name: "valid", rules: []Redirect{{From: "/a", To: "/b"}}},
+		{name: "relative from", rules: []Redirect{{From: "a", To: "/b"}}, wantErr: "must begin with '/'"},
+		{name: "no destination", rules: []Redirect{{From: "/a"}}, wantErr: "no destination"},
+		{name: "protocol relative", rules: []Redirect{{From: "/a", To: "//evil.com"}}, wantErr: "protocol relative"},
+		{name: "backslash protocol relative", rules: []Redirect{{From: "/a", To: "/\\evil.com"}}, wantErr: "protocol relative"},
+		{name: "unknown match", rules: []Redirect{{From: "/a", To: "/b", Match: "regexp"}}, wantErr: "unknown match"},
+		{name: "bad code", rules: []Redirect{{From: "/a", To: "/b", Code: http.StatusOK}}, wantErr: "code 200"},
+		{name: "unknown parameter", rules: []Redirect{{From: "/:a", To: "/:b", Match: RedirectPattern}}, wantErr: "not a parameter"},
+		{name: "invalid pattern", rules: []Redirect{{From: "/*/a", To: "/b", Match: RedirectPattern}}, wantErr: "invalid redirect"},
+		{name: "conflict", rules: []Redirect{{From: "/a", To: "/b"}, {From: "/a", To: "/c"}}, wantErr: "conflicts"},
+		{name: "alias conflict", rules: []Redirect{{From: "/a", To: "/b"}}, posts: DateSortable{{Path: "p", Aliases: []string{"/a"}}}, wantErr: "conflicts"},
+		{name: "self loop", rules: []Redirect{{From: "/a", To: "/a"}}, wantErr: "redirect loop"},
+		{name: "loop", rules: []Redirect{{From: "/a", To: "/b"}, {From: "/b", To: "/a"}}, wantErr: "redirect loop"},
+		{name: "loop with query", rules: []Redirect{{From: "/a", To: "/b?x=1"}, {From: "/b", To: "/a#top"}}, wantErr: "redirect loop"},
+		{
+			name:    "loop through an alias",
+			rules:   []Redirect{{From: "/p", To: "/old"}},
+			posts:   DateSortable{{Path: "p", Aliases: []string{"/old"}}},
+			wantErr: "redirect loop",
+		},
+		{
+			name:    "loop through a prefix",
+			rules:   []Redirect{{From: "/a", To: "/b", Match: RedirectPrefix}, {From: "/b/x", To: "/a/x"}},
+			wantErr: "redirect loop",
+		},
+		{
+			name:    "loop through a pattern",
+			rules:   []Redirect{{From: "/a/:slug", To: "/b/:slug", Match: RedirectPattern}, {From: "/b/x", To: "/a/x"}},
+			wantErr: "redirect loop",
+		},
+		{name: "absolute urls end chains", rules: []Redirect{{From: "/a", To: "https://example.com/a"}}},
+		{name: "longest chain", rules: chain(maxRedirectHops - 1)},
+		{name: "chain too long", rules: chain(maxRedirectHops), wantErr: "exceeds"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewRedirects(tc.rules, tc.posts)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("got error %v", err)
+			case tc.wantErr != "" && err == nil:
+				t.Fatalf("got no error, want %q", tc.wantErr)
+			case tc.wantErr != "" && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedirectsScheduledAlias(t *testing.T) {
+	now := time.Now()
+	posts := DateSortable{
+		{Path: "posts/live.post", Aliases: []string{"/old/live"}},
+		{Path: "posts/soon.post", Aliases: []string{"/old/soon"}, PublishAt: now.Add(time.Hour)},
+	}
+	rs, err := NewRedirects([]Redirect{{From: "/old", To: "/archive", Match: RedirectPrefix}}, posts)
+	if err != nil {
+		t.Fatalf("NewRedirects: %v", err)
+	}
+
+	tt := []struct {
+		name string
+		path string
+		at   time.Time
+		want string
+	}{
+		{name: "published", path: "/old/live", at: now, want: "/posts/live.post"},
+		{name: "scheduled", path: "/old/soon", at: now, want: "/archive/soon"},
+		{name: "scheduled once published", path: "/old/soon", at: now.Add(time.Hour), want: "/posts/soon.post"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, ok := rs.lookup(tc.path, tc.at)
+			if !ok || got != tc.want {
+				t.Errorf("got %q, %v, want %q", got, ok, tc.want)
+			}
+		})
+	}
+}
+
+func TestRedirectsScheduledAliasLoop(t *testing.T) {
+	// the loop only exists once the post is published.
+	posts := DateSortable{
+		{Path: "a", Aliases: []string{"/b"}, PublishAt: time.Now().Add(time.Hour)},
+	}
+	if _, err := NewRedirects([]Redirect{{From: "/a", To: "/b"}}, posts); err == nil {
+		t.Error("expected a redirect loop error")
+	}
+}
@@ -0,0 +1,185 @@
+package goblog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Route is a compiled path pattern.
+//
+// Patterns are made of "/" separated segments, each of
+// which is one of:
+//
+//	literal - matches itself exactly, such as "post"
+//	:name   - a named parameter matching any single segment
+//	*       - matches the remainder of the path, which may be
+//	          empty, and may only appear as the last segment
+//
+// A trailing "*" may also be named, "*rest", capturing the
+// remainder of the path as a parameter. Patterns without a
+// wildcard match paths with exactly as many segments.
+type Route struct {
+	pattern  string
+	segments []string
+}
+
+// ParseRoute compiles pattern into a Route.
+func ParseRoute(pattern string) (Route, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return Route{}, fmt.Errorf("invalid route %q: must begin with '/'", pattern)
+	}
+	rt := Route{pattern: pattern}
+	if pattern == "/" {
+		return rt, nil
+	}
+	rt.segments = strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	names := map[string]bool{}
+	for i, seg := range rt.segments {
+		switch {
+		case seg == "" && i != len(rt.segments)-1:
+			return Route{}, fmt.Errorf("invalid route %q: empty segment", pattern)
+		case strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*"):
+			if strings.HasPrefix(seg, "*") && i != len(rt.segments)-1 {
+				return Route{}, fmt.Errorf("invalid route %q: wildcards must be the last segment", pattern)
+			}
+			name := seg[1:]
+			if strings.HasPrefix(seg, ":") && name == "" {
+				return Route{}, fmt.Errorf("invalid route %q: parameters must be named", pattern)
+			}
+			if name != "" && names[name] {
+				return Route{}, fmt.Errorf("invalid route %q: duplicate parameter %q", pattern, name)
+			}
+			names[name] = true
+		case strings.ContainsAny(seg, ":*"):
+			return Route{}, fmt.Errorf("invalid route %q: ':' and '*' may only begin a segment", pattern)
+		}
+	}
+	return rt, nil
+}
+
+// String returns the pattern rt was compiled from.
+func (rt Route) String() string {
+	return rt.pattern
+}
+
+// Match reports whether p matches rt, returning the values
+// of any named parameters.
+func (rt Route) Match(p string) (map[string]string, bool) {
+	if !strings.HasPrefix(p, "/") {
+		return nil, false
+	}
+	if p == "/" && len(rt.segments) == 0 {
+		return map[string]string{}, true
+	}
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	params := map[string]string{}
+	for i, seg := range rt.segments {
+		if strings.HasPrefix(seg, "*") {
+			if name := seg[1:]; name != "" {
+				params[name] = strings.Join(parts[i:], "/")
+			}
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			if parts[i] == "" {
+				return nil, false
+			}
+			params[seg[1:]] = parts[i]
+		case seg != parts[i]:
+			return nil, false
+		}
+	}
+	if len(parts) != len(rt.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// specificity ranks how narrowly rt matches paths, routes
+// without a wildcard are the most specific followed by
+// those with the most literal, then total, segments.
+func (rt Route) specificity() [3]int {
+	var s [3]int
+	s[0] = 1
+	for _, seg := range rt.segments {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			s[0] = 0
+		case strings.HasPrefix(seg, ":"):
+		default:
+			s[1]++
+		}
+	}
+	s[2] = len(rt.segments)
+	return s
+}
+
+func moreSpecific(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return false
+}
+
+// AppRoutes is a compiled set of app path patterns, see
+// Config.AppPaths.
+type AppRoutes []appRoute
+
+type appRoute struct {
+	Route
+	negate bool
+}
+
+// ParseAppRoutes compiles app path patterns. Patterns are
+// Route patterns which may be prefixed with "!" to exclude
+// the paths they match.
+func ParseAppRoutes(patterns []string) (AppRoutes, error) {
+	routes := make(AppRoutes, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		var ar appRoute
+		if strings.HasPrefix(pattern, "!") {
+			ar.negate = true
+			pattern = pattern[1:]
+		}
+		rt, err := ParseRoute(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid app path: %w", err)
+		}
+		ar.Route = rt
+		routes = append(routes, ar)
+	}
+	return routes, nil
+}
+
+// Match reports whether p is served by the front-end
+// application. The most specific pattern matching p decides,
+// when an including and excluding pattern are equally
+// specific the exclusion wins.
+//
+// ok reports whether any pattern matched p at all.
+func (ar AppRoutes) Match(p string) (app bool, ok bool) {
+	var best [3]int
+	for _, r := range ar {
+		if _, match := r.Route.Match(p); !match {
+			continue
+		}
+		s := r.specificity()
+		switch {
+		case !ok, moreSpecific(s, best):
+			app = !r.negate
+		case s == best && r.negate:
+			app = false
+		default:
+			continue
+		}
+		ok, best = true, s
+	}
+	return app, ok
+}
@@ -0,0 +1,128 @@
+package goblog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoute(t *testing.T) {
+	tt := []struct {
+		pattern string
+		hasErr  bool
+	}{
+		{pattern: "/"},
+		{pattern: "/about"},
+		{pattern: "/about/"},
+		{pattern: "/post/:slug"},
+		{pattern: "/archive/*"},
+		{pattern: "/files/*rest"},
+		{pattern: "/:year/:slug/*"},
+		{pattern: "about", hasErr: true},
+		{pattern: "", hasErr: true},
+		{pattern: "/a//b", hasErr: true},
+		{pattern: "/*/edit", hasErr: true},
+		{pattern: "/post/:", hasErr: true},
+		{pattern: "/:slug/:slug", hasErr: true},
+		{pattern: "/:rest/*rest", hasErr: true},
+		{pattern: "/post:slug", hasErr: true},
+		{pattern: "/a*", hasErr: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.pattern, func(t *testing.T) {
+			rt, err := ParseRoute(tc.pattern)
+			if (err != nil) != tc.hasErr {
+				t.Fatalf("got error %v, want error %v", err, tc.hasErr)
+			}
+			if err == nil && rt.String() != tc.pattern {
+				t.Errorf("got String %q, want %q", rt.String(), tc.pattern)
+			}
+		})
+	}
+}
+
+func TestRouteMatch(t *testing.T) {
+	tt := []struct {
+		name    string
+		pattern string
+		path    string
+		want    map[string]string
+		noMatch bool
+	}{
+		{name: "root", pattern: "/", path: "/", want: map[string]string{}},
+		{name: "root is exact", pattern: "/", path: "/about", noMatch: true},
+		{name: "literal", pattern: "/about", path: "/about", want: map[string]string{}},
+		{name: "literal is exact", pattern: "/about", path: "/about/team", noMatch: true},
+		{name: "literal trailing slash", pattern: "/about", path: "/about/", noMatch: true},
+		{name: "relative path", pattern: "/about", path: "about", noMatch: true},
+		{name: "param", pattern: "/post/:slug", path: "/post/hello", want: map[string]string{"slug": "hello"}},
+		{name: "param is one segment", pattern: "/post/:slug", path: "/post/hello/world", noMatch: true},
+		{name: "param is not empty", pattern: "/post/:slug", path: "/post/", noMatch: true},
+		{name: "param is required", pattern: "/post/:slug", path: "/post", noMatch: true},
+		{name: "wildcard", pattern: "/archive/*", path: "/archive/2021/06", want: map[string]string{}},
+		{name: "wildcard one segment", pattern: "/archive/*", path: "/archive/2021", want: map[string]string{}},
+		{name: "wildcard empty", pattern: "/archive/*", path: "/archive", want: map[string]string{}},
+		{name: "wildcard trailing slash", pattern: "/archive/*", path: "/archive/", want: map[string]string{}},
+		{name: "wildcard prefix is a segment", pattern: "/archive/*", path: "/archives", noMatch: true},
+		{name: "named wildcard", pattern: "/files/*rest", path: "/files/a/b.txt", want: map[string]string{"rest": "a/b.txt"}},
+		{name: "named wildcard empty", pattern: "/files/*rest", path: "/files", want: map[string]string{"rest": ""}},
+		{name: "params and wildcard", pattern: "/:year/:slug/*", path: "/2021/hello/comments/1", want: map[string]string{"year": "2021", "slug": "hello"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rt, err := ParseRoute(tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := rt.Match(tc.path)
+			if ok == tc.noMatch {
+				t.Fatalf("got match %v, want %v", ok, !tc.noMatch)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got params %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppRoutesMatch(t *testing.T) {
+	tt := []struct {
+		name     string
+		patterns []string
+		path     string
+		app, ok  bool
+	}{
+		{name: "no patterns", path: "/about"},
+		{name: "no match", patterns: []string{"/about"}, path: "/contact"},
+		{name: "include", patterns: []string{"/post/:slug"}, path: "/post/hello", app: true, ok: true},
+		{name: "exclude", patterns: []string{"!/about"}, path: "/about", ok: true},
+		{name: "exact beats wildcard", patterns: []string{"/archive/*", "!/archive/raw"}, path: "/archive/raw", ok: true},
+		{name: "exact beats param", patterns: []string{"!/post/:slug", "/post/new"}, path: "/post/new", app: true, ok: true},
+		{name: "param beats wildcard", patterns: []string{"!/post/*", "/post/:slug"}, path: "/post/hello", app: true, ok: true},
+		{name: "wildcard below exclusion", patterns: []string{"/archive/*", "!/archive/raw"}, path: "/archive/raw/1", app: true, ok: true},
+		{name: "more literal segments win", patterns: []string{"!/:a/:b", "/post/:slug"}, path: "/post/hello", app: true, ok: true},
+		{name: "longer wildcard wins", patterns: []string{"/*", "!/static/*"}, path: "/static/app.js", ok: true},
+		{name: "exclusion wins ties", patterns: []string{"/post/:slug", "!/post/:id"}, path: "/post/1", ok: true},
+		{name: "exclusion wins ties in any order", patterns: []string{"!/post/:id", "/post/:slug"}, path: "/post/1", ok: true},
+		{name: "spaces trimmed", patterns: []string{" /about "}, path: "/about", app: true, ok: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ar, err := ParseAppRoutes(tc.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app, ok := ar.Match(tc.path)
+			if app != tc.app || ok != tc.ok {
+				t.Errorf("got %v, %v, want %v, %v", app, ok, tc.app, tc.ok)
+			}
+		})
+	}
+}
+
+func TestParseAppRoutesInvalid(t *testing.T) {
+	for _, patterns := range [][]string{{"about"}, {"/post/:"}, {"!"}, {"/ok", "/*/bad"}} {
+		if _, err := ParseAppRoutes(patterns); err == nil {
+			t.Errorf("%q: expected an error", patterns)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package goblog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxScheduleWait bounds how long WatchSchedule sleeps
+// between re-evaluations, tolerating clock changes.
+const maxScheduleWait = time.Minute
+
+// publishedPosts holds the posts of a content snapshot whose
+// PublishAt has passed along with when the next scheduled
+// post is due.
+type publishedPosts struct {
+	sync.RWMutex
+	posts DateSortable
+	next  time.Time
+}
+
+// Published returns the posts being served which have been
+// published, newest first.
+//
+// Scheduled posts appear once WatchSchedule notices their
+// PublishAt has passed. The returned slice must not be
+// modified.
+func Published() DateSortable {
+	return loaded().publishedPosts()
+}
+
+func (c *content) publishedPosts() DateSortable {
+	c.published.RLock()
+	defer c.published.RUnlock()
+	return c.published.posts
+}
+
+// refreshPublished re-evaluates which posts of c are
+// published as of now, returning when the next scheduled
+// post is due or the zero time if none are.
+func (c *content) refreshPublished(now time.Time) time.Time {
+	var next time.Time
+	posts := c.posts.Filter(func(p Post) bool {
+		if p.IsPublished(now) {
+			return true
+		}
+		if next.IsZero() || p.PublishAt.Before(next) {
+			next = p.PublishAt
+		}
+		return false
+	})
+	c.published.Lock()
+	c.published.posts, c.published.next = posts, next
+	c.published.Unlock()
+	return next
+}
+
+// WatchSchedule re-evaluates Published whenever a scheduled
+// post comes due, returning once ctx is done.
+func WatchSchedule(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := loaded().refreshPublished(now)
+		wait := maxScheduleWait
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package goblog
+
+import (
+	"encoding/json"
+	"html"
+	"io/fs"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Index is a full-text SearchIndex over every post in DSCache.
+var Index *SearchIndex
+
+// field weights applied to term frequencies when scoring.
+const (
+	titleWeight   = 3.0
+	summaryWeight = 2.0
+	bodyWeight    = 1.0
+)
+
+// snippetLen is the approximate length, in bytes, of the
+// snippets returned with search results.
+const snippetLen = 160
+
+// stopWords are common words not worth indexing.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "from": true, "has": true,
+	"have": true, "i": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "our": true,
+	"so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "these": true, "this": true, "to": true, "was": true,
+	"we": true, "were": true, "will": true, "with": true, "you": true, "your": true,
+}
+
+// token is a word found in a body of text along with its
+// byte offsets.
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits s into lower cased, stemmed terms,
+// dropping stop words.
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(s[start:end])
+		tok := token{term: stem(word), start: start, end: end}
+		start = -1
+		if stopWords[word] {
+			return
+		}
+		tokens = append(tokens, tok)
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(s))
+	return tokens
+}
+
+// stem is a deliberately small suffix stripper, enough for
+// "posts", "posting" and "posted" to all match "post".
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "edly", "ed", "ies", "es", "ly", "s"} {
+		if !strings.HasSuffix(word, suffix) || len(word)-len(suffix) < 3 {
+			continue
+		}
+		word = strings.TrimSuffix(word, suffix)
+		if suffix == "ies" {
+			word += "y"
+		}
+		break
+	}
+	return word
+}
+
+// posting records the weighted frequency of a term
+// within a single document.
+type posting struct {
+	doc    int
+	weight float64
+}
+
+// searchDoc is an indexed post.
+type searchDoc struct {
+	post Post
+	// body is the plain text of the post's markdown body.
+	body string
+	// length is the number of tokens in the document and
+	// is used to normalize scores.
+	length int
+}
+
+// SearchIndex is an in-memory inverted index over the
+// title, summary and the text of the markdown body of posts.
+type SearchIndex struct {
+	docs  []searchDoc
+	terms map[string][]posting
+}
+
+// SearchResult is a post matching a search query.
+type SearchResult struct {
+	Post
+	Score float64 `json:"score"`
+	// Snippet is an HTML escaped excerpt of the post's body
+	// with matching terms wrapped in <mark> elements.
+	Snippet string `json:"snippet"`
+}
+
+// NewSearchIndex indexes the provided posts, reading each
+// post's body from PostsFS.
+func NewSearchIndex(posts DateSortable) (*SearchIndex, error) {
+	return newSearchIndex(PostsFS, posts)
+}
+
+func newSearchIndex(fsys fs.FS, posts DateSortable) (*SearchIndex, error) {
+	idx := &SearchIndex{
+		docs:  make([]searchDoc, 0, len(posts)),
+		terms: map[string][]posting{},
+	}
+	for i, post := range posts {
+		full, err := readPost(fsys, post.Path)
+		if err != nil {
+			return nil, err
+		}
+		body := plainText(full.MarkDown.Value)
+
+		weights := map[string]float64{}
+		length := 0
+		for _, field := range []struct {
+			text   string
+			weight float64
+		}{
+			{post.Title, titleWeight},
+			{post.Summary, summaryWeight},
+			{body, bodyWeight},
+		} {
+			for _, tok := range tokenize(field.text) {
+				weights[tok.term] += field.weight
+				length++
+			}
+		}
+		for term, w := range weights {
+			idx.terms[term] = append(idx.terms[term], posting{doc: i, weight: w})
+		}
+		idx.docs = append(idx.docs, searchDoc{post: post, body: body, length: length})
+	}
+	return idx, nil
+}
+
+// Search returns the published posts matching any term in
+// query, ranked by a tf-idf score.
+//
+// At most limit results are returned, all of them when limit
+// is zero.
+func (idx *SearchIndex) Search(query string, limit int) []SearchResult {
+	now := time.Now()
+	queryTerms := map[string]bool{}
+	for _, tok := range tokenize(query) {
+		queryTerms[tok.term] = true
+	}
+
+	scores := map[int]float64{}
+	for term := range queryTerms {
+		postings := idx.terms[term]
+		if len(postings) == 0 {
+			continue
+		}
+		idf := math.Log(1 + float64(len(idx.docs))/float64(len(postings)))
+		for _, p := range postings {
+			tf := p.weight / math.Sqrt(float64(idx.docs[p.doc].length))
+			scores[p.doc] += tf * idf
+		}
+	}
+
+	type match struct {
+		doc   int
+		score float64
+	}
+	matches := make([]match, 0, len(scores))
+	for doc, score := range scores {
+		if !idx.docs[doc].post.IsPublished(now) {
+			continue
+		}
+		matches = append(matches, match{doc: doc, score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return idx.docs[matches[i].doc].post.Date.After(idx.docs[matches[j].doc].post.Date)
+	})
+	if limit > 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	// snippets are only built for the results returned,
+	// each one tokenizes the post's body again.
+	results := make([]SearchResult, 0, len(matches))
+	for _, m := range matches {
+		d := idx.docs[m.doc]
+		results = append(results, SearchResult{
+			Post:    d.post,
+			Score:   m.score,
+			Snippet: snippet(d.body, queryTerms),
+		})
+	}
+	return results
+}
+
+// snippet excerpts body around the first occurrence of a
+// query term, HTML escaping the text and marking matches.
+func snippet(body string, queryTerms map[string]bool) string {
+	tokens := tokenize(body)
+	first := -1
+	for i, tok := range tokens {
+		if queryTerms[tok.term] {
+			first = i
+			break
+		}
+	}
+
+	start := 0
+	if first >= 0 {
+		start = tokens[first].start - snippetLen/4
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLen
+	if end > len(body) {
+		end = len(body)
+	}
+	// don't cut words or runes in half.
+	for start > 0 && !isBoundary(body, start) {
+		start--
+	}
+	for end < len(body) && !isBoundary(body, end) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, tok := range tokens {
+		if tok.start < start || tok.end > end || !queryTerms[tok.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(body[pos:tok.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(body[tok.start:tok.end]))
+		b.WriteString("</mark>")
+		pos = tok.end
+	}
+	b.WriteString(html.EscapeString(body[pos:end]))
+	if end < len(body) {
+		b.WriteString("…")
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func isBoundary(s string, i int) bool {
+	r := rune(s[i])
+	return r < 0x80 && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// SearchHandler serves full-text search results for the
+// "q" query parameter, ranked by relevance.
+//
+// The "limit" query parameter bounds the number of results.
+func SearchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query().Get("q")
+		if strings.TrimSpace(q) == "" {
+			Error(w, r, "no search query provided in q param", http.StatusBadRequest)
+			return
+		}
+		lim, err := parseLimit(r)
+		if err != nil {
+			Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results := contentOf(r).index.Search(q, lim)
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(results)
+		if err != nil {
+			Error(w, r, "failed serializing: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package goblog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// default security headers used when SecurityHeaders leaves
+// a field empty.
+const (
+	defaultContentSecurityPolicy = "default-src 'self'; " +
+		"script-src 'self' 'nonce-{nonce}'; " +
+		"style-src 'self' 'unsafe-inline'; " +
+		"img-src 'self' data: https:; " +
+		"media-src 'self' https:; " +
+		"object-src 'none'; " +
+		"base-uri 'self'; " +
+		"frame-ancestors 'none'"
+	defaultContentTypeOptions = "nosniff"
+	defaultReferrerPolicy     = "strict-origin-when-cross-origin"
+	defaultPermissionsPolicy  = "camera=(), microphone=(), geolocation=(), interest-cohort=()"
+	defaultFrameOptions       = "DENY"
+)
+
+// nonceKey is the context key holding a request's
+// CSP nonce.
+type nonceKey struct{}
+
+// Nonce returns the Content-Security-Policy nonce
+// generated for the request with ctx, if any.
+func Nonce(ctx context.Context) string {
+	n, _ := ctx.Value(nonceKey{}).(string)
+	return n
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// securityHeaderSet is a resolved set of headers.
+type securityHeaderSet struct {
+	contentSecurityPolicy string
+	contentTypeOptions    string
+	referrerPolicy        string
+	permissionsPolicy     string
+	frameOptions          string
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func orBase(v, base string) string {
+	if v == "" {
+		return base
+	}
+	return v
+}
+
+// resolve returns the headers for a request path.
+func (s SecurityHeaders) resolve(p string) securityHeaderSet {
+	set := securityHeaderSet{
+		contentSecurityPolicy: orDefault(s.ContentSecurityPolicy, defaultContentSecurityPolicy),
+		contentTypeOptions:    orDefault(s.ContentTypeOptions, defaultContentTypeOptions),
+		referrerPolicy:        orDefault(s.ReferrerPolicy, defaultReferrerPolicy),
+		permissionsPolicy:     orDefault(s.PermissionsPolicy, defaultPermissionsPolicy),
+		frameOptions:          orDefault(s.FrameOptions, defaultFrameOptions),
+	}
+	var match *SecurityHeadersOverride
+	for i, o := range s.Overrides {
+		if !strings.HasPrefix(p, o.PathPrefix) {
+			continue
+		}
+		if match == nil || len(o.PathPrefix) > len(match.PathPrefix) {
+			match = &s.Overrides[i]
+		}
+	}
+	if match != nil {
+		set.contentSecurityPolicy = orBase(match.ContentSecurityPolicy, set.contentSecurityPolicy)
+		set.contentTypeOptions = orBase(match.ContentTypeOptions, set.contentTypeOptions)
+		set.referrerPolicy = orBase(match.ReferrerPolicy, set.referrerPolicy)
+		set.permissionsPolicy = orBase(match.PermissionsPolicy, set.permissionsPolicy)
+		set.frameOptions = orBase(match.FrameOptions, set.frameOptions)
+	}
+	return set
+}
+
+// SecurityHeadersHandler wraps h, setting the security
+// headers configured for each request's path.
+//
+// If the resolved Content-Security-Policy references
+// "{nonce}" a fresh nonce is generated and made available
+// to h via Nonce.
+func SecurityHeadersHandler(conf SecurityHeaders, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set := conf.resolve(r.URL.Path)
+
+		csp := set.contentSecurityPolicy
+		if strings.Contains(csp, "{nonce}") {
+			nonce, err := newNonce()
+			if err != nil {
+				Error(w, r, "failed generating nonce: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			csp = strings.ReplaceAll(csp, "{nonce}", nonce)
+			r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce))
+		}
+
+		hdr := w.Header()
+		for _, kv := range [][2]string{
+			{"Content-Security-Policy", csp},
+			{"X-Content-Type-Options", set.contentTypeOptions},
+			{"Referrer-Policy", set.referrerPolicy},
+			{"Permissions-Policy", set.permissionsPolicy},
+			{"X-Frame-Options", set.frameOptions},
+		} {
+			if kv[1] != "-" {
+				hdr.Set(kv[0], kv[1])
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// nonceableTag matches the opening tag of script and style
+// elements.
+var nonceableTag = regexp.MustCompile(`(?i)<(script|style)(\s[^>]*)?>`)
+
+// nonceAttr matches a nonce attribute within an opening tag.
+var nonceAttr = regexp.MustCompile(`(?i)\snonce\s*=`)
+
+// injectNonce adds a nonce attribute to every script and
+// style element in an html document which lacks one.
+func injectNonce(doc []byte, nonce string) []byte {
+	attr := []byte(` nonce="` + nonce + `"`)
+	return nonceableTag.ReplaceAllFunc(doc, func(tag []byte) []byte {
+		if nonceAttr.Match(tag) {
+			return tag
+		}
+		// tag is "<script" or "<style" followed by its
+		// attributes, if any, and a closing bracket.
+		i := bytes.IndexAny(tag, " \t\r\n\f>")
+		var b bytes.Buffer
+		b.Write(tag[:i])
+		b.Write(attr)
+		b.Write(tag[i:])
+		return b.Bytes()
+	})
+}
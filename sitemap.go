@@ -0,0 +1,83 @@
+package goblog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sitemap is a sitemaps.org urlset document.
+type sitemap struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SitemapHandler serves a sitemap.xml listing the blog's
+// root, the literal AppPaths and every published post.
+func SitemapHandler(conf Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		base := baseURL(conf, r)
+
+		doc := sitemap{}
+		root := sitemapURL{Loc: base + "/"}
+		posts := contentOf(r).publishedPosts()
+		if len(posts) > 0 {
+			root.LastMod = posts[0].Date.Format(time.RFC3339)
+		}
+		doc.URLs = append(doc.URLs, root)
+		for _, appPath := range conf.AppPaths {
+			// only literal app paths address a single page.
+			if appPath == "" || appPath == "/" || strings.ContainsAny(appPath, ":*!") {
+				continue
+			}
+			doc.URLs = append(doc.URLs, sitemapURL{Loc: absURL(base, appPath)})
+		}
+		for _, post := range posts {
+			doc.URLs = append(doc.URLs, sitemapURL{
+				Loc:     absURL(base, post.Path),
+				LastMod: post.Date.Format(time.RFC3339),
+			})
+		}
+
+		writeXML(w, r, "application/xml; charset=UTF-8", doc)
+	}
+}
+
+// RobotsHandler serves a robots.txt built from the Robots
+// section of the provided Config.
+func RobotsHandler(conf Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var b strings.Builder
+		b.WriteString("User-agent: *\n")
+		for _, p := range conf.Robots.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", p)
+		}
+		for _, p := range conf.Robots.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", p)
+		}
+		// an empty Disallow explicitly allows everything.
+		if len(conf.Robots.Disallow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+		fmt.Fprintf(&b, "\nSitemap: %s\n", absURL(baseURL(conf, r), "sitemap.xml"))
+
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.Write([]byte(b.String()))
+	}
+}
@@ -0,0 +1,217 @@
+package goblog
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// TemplatesFS holds the html/template theme used when
+// GoBlog renders pages on the server, rooted at "templates/".
+//
+// Forks may restyle their blog by editing the templates
+// in this directory. Each page template defines a
+// "content" block, and optionally a "title" block, which
+// are placed into the "base" layout found in base.html.
+//
+// It is the theme embedded when GoBlog was built unless
+// replaced by LoadContent.
+var TemplatesFS fs.FS = embeddedTemplates
+
+// theme pages, each parsed alongside base.html.
+const (
+	indexPage   = "index.html"
+	postPage    = "post.html"
+	archivePage = "archive.html"
+	tagPage     = "tag.html"
+)
+
+// routes served by a Theme.
+const (
+	themePostPath    = "/post/"
+	themeTagPath     = "/tag/"
+	themeArchivePath = "/archive"
+)
+
+// Theme renders blog pages with html/template.
+type Theme struct {
+	conf  Config
+	pages map[string]*template.Template
+}
+
+// themeFuncs are the functions available to theme
+// templates.
+var themeFuncs = template.FuncMap{
+	"postURL": func(p Post) string { return themePostPath + url.PathEscape(p.Slug) },
+	"tagURL":  func(tag string) string { return themeTagPath + url.PathEscape(tag) },
+	"date":    func(t time.Time) string { return t.Format("January 2, 2006") },
+	"rfc3339": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"heroSrc": func(hero string) string { return heroURL("", hero) },
+}
+
+// NewTheme parses the templates found in the "templates"
+// directory of fsys.
+func NewTheme(fsys fs.FS, conf Config) (*Theme, error) {
+	if conf.Title == "" {
+		conf.Title = "goblog"
+	}
+	t := &Theme{conf: conf, pages: map[string]*template.Template{}}
+	for _, page := range []string{indexPage, postPage, archivePage, tagPage} {
+		tmpl, err := template.New(page).Funcs(themeFuncs).ParseFS(fsys, "templates/base.html", "templates/"+page)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %v template: %w", page, err)
+		}
+		t.pages[page] = tmpl
+	}
+	return t, nil
+}
+
+// themeData is provided to every theme template.
+type themeData struct {
+	Site  Config
+	Nonce string
+
+	// index, tag and archive pages
+	Posts DateSortable
+	Tag   string
+	Tags  []TagCount
+	Years []yearPosts
+	// index pages link to neighbouring pages
+	Older, Newer string
+
+	// post pages
+	Post Post
+	Body template.HTML
+	// the neighbouring posts of Post, if any
+	OlderPost, NewerPost *Post
+}
+
+// yearPosts groups the archive by year.
+type yearPosts struct {
+	Year  int
+	Posts DateSortable
+}
+
+// Handler serves server rendered pages of published posts:
+//
+//	/              - the latest posts, paged with "page"
+//	/post/{slug}   - a single post
+//	/archive       - every post grouped by year
+//	/tag/{tag}     - the posts tagged with tag
+//
+// Requests for any other path are passed to fallback,
+// typically a WebHandler serving static assets.
+func (t *Theme) Handler(fallback http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		switch {
+		case p == "/":
+		case p == themeArchivePath:
+		case strings.HasPrefix(p, themePostPath):
+		case strings.HasPrefix(p, themeTagPath):
+		default:
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data := themeData{Site: t.conf, Nonce: Nonce(r.Context())}
+		c := contentOf(r)
+		posts := c.publishedPosts()
+		var page string
+		switch {
+		case p == "/":
+			page = indexPage
+			n := r.URL.Query().Get("page")
+			if n == "" {
+				n = "1"
+			}
+			// only "page" is honored, the links between
+			// pages carry nothing else.
+			pg, err := parsePaginationQuery(url.Values{"page": {n}})
+			if err != nil {
+				Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			res := pg.apply(posts)
+			if pg.page > 1 && len(res.posts) == 0 {
+				Error(w, r, "not found", http.StatusNotFound)
+				return
+			}
+			data.Posts = res.posts
+			if res.older {
+				data.Older = "/?page=" + strconv.Itoa(pg.page+1)
+			}
+			if res.newer {
+				data.Newer = "/?page=" + strconv.Itoa(pg.page-1)
+				if pg.page == 2 {
+					data.Newer = "/"
+				}
+			}
+		case p == themeArchivePath:
+			page = archivePage
+			for _, post := range posts {
+				year := post.Date.Year()
+				if len(data.Years) == 0 || data.Years[len(data.Years)-1].Year != year {
+					data.Years = append(data.Years, yearPosts{Year: year})
+				}
+				y := &data.Years[len(data.Years)-1]
+				y.Posts = append(y.Posts, post)
+			}
+			data.Tags = posts.TagCounts()
+		case strings.HasPrefix(p, themeTagPath):
+			page = tagPage
+			data.Tag = strings.TrimPrefix(p, themeTagPath)
+			data.Posts = posts.Filter(func(post Post) bool { return post.HasTag(data.Tag) })
+			if data.Tag == "" || len(data.Posts) == 0 {
+				Error(w, r, "not found", http.StatusNotFound)
+				return
+			}
+		case strings.HasPrefix(p, themePostPath):
+			page = postPage
+			i, ok := posts.BySlug(strings.TrimPrefix(p, themePostPath))
+			if !ok {
+				Error(w, r, "not found", http.StatusNotFound)
+				return
+			}
+			data.Post = posts[i]
+			body, err := c.renderPost(data.Post.Path)
+			if err != nil {
+				Error(w, r, "failed rendering post: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// RenderPost omits raw HTML, its output is safe.
+			data.Body = template.HTML(body)
+			if i+1 < len(posts) {
+				data.OlderPost = &posts[i+1]
+			}
+			if i > 0 {
+				data.NewerPost = &posts[i-1]
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := t.pages[page].ExecuteTemplate(&buf, "base", data); err != nil {
+			Error(w, r, "failed rendering page: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if data.Nonce != "" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		serveBytes(w, r, "text/html; charset=utf-8", "", buf.Bytes())
+	}
+}
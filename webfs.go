@@ -2,7 +2,14 @@ package goblog
 
 import (
 	"embed"
+	"io/fs"
 )
 
 //go:embed web
-var WebFS embed.FS
+var embeddedWeb embed.FS
+
+// WebFS holds your web application, rooted at "web/".
+//
+// It is the web root embedded when GoBlog was built unless
+// replaced by LoadContent.
+var WebFS fs.FS = embeddedWeb